@@ -0,0 +1,88 @@
+package crumb
+
+import (
+    "fmt"
+    "log"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+var aliasParamRe = regexp.MustCompile(`\$(\d+|@)`)
+
+// How many times a single alias may expand in one invocation before it is
+// considered recursive
+const maxAliasExpansions = 32
+
+var aliasExpansions = make(map[string]int)
+
+// Replaces $1..$N with the args following the alias and $@ with the rest
+func expandAlias(alias AliasDesc, args *SimpleStack) []string {
+    aliasExpansions[alias.Name]++
+    if aliasExpansions[alias.Name] > maxAliasExpansions {
+        log.Fatal(fmt.Sprintf("Alias %s expands recursively", alias.Name))
+    }
+
+    params, rest := 0, false
+    for _, arg := range alias.Args {
+        for _, match := range aliasParamRe.FindAllStringSubmatch(arg, -1) {
+            if match[1] == "@" {
+                rest = true
+            } else if n, _ := strconv.Atoi(match[1]); n > params {
+                params = n
+            }
+        }
+    }
+
+    if args.Size() < params {
+        log.Fatal(fmt.Sprintf("Alias %s needs %d args", alias.Name, params))
+    }
+    positional := []string{alias.Name}
+    for i := 0; i < params; i++ {
+        positional = append(positional, args.Pop())
+    }
+    var restArgs []string
+    if rest {
+        restArgs = args.Empty()
+    }
+
+    var expanded []string
+    for _, arg := range alias.Args {
+        if arg == "$@" {
+            expanded = append(expanded, restArgs...)
+            continue
+        }
+        expanded = append(expanded, aliasParamRe.ReplaceAllStringFunc(arg, func (param string) string {
+            if param == "$@" {
+                return strings.Join(restArgs, " ")
+            }
+            n, _ := strconv.Atoi(param[1:])
+            return positional[n]
+        }))
+    }
+    return expanded
+}
+
+func aliasHelp(alias AliasDesc) string {
+    help := fmt.Sprintf("%s -> %s", alias.Name, strings.Join(alias.Args, " "))
+    if alias.Description != "" {
+        help += "\n        " + alias.Description
+    }
+    return help
+}
+
+func printAliases(conf *Config) {
+    if len(conf.Alias) == 0 {
+        return
+    }
+    aliases := append([]AliasDesc{}, conf.Alias...)
+    sort.SliceStable(aliases, func (i, j int) bool {
+        return aliases[i].Name < aliases[j].Name
+    })
+
+    fmt.Println("\nALIASES:")
+    for _, alias := range aliases {
+        fmt.Println("    " + aliasHelp(alias))
+    }
+}
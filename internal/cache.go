@@ -0,0 +1,111 @@
+package crumb
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+)
+
+// Dir listings of earlier walks, a listing is reused as long as its dir's
+// modification time is unchanged. Adding or removing a crumb file or dir
+// changes it, editing a crumb file does not need to as files are always read.
+type dirCache struct {
+    mu sync.Mutex
+    path string
+    CrumbFileName string
+    Dirs map[string]dirListing
+    dirty bool
+    walked map[string]bool
+}
+
+var walkCache = &dirCache{}
+
+func walkCachePath() string {
+    cacheHome := os.Getenv("XDG_CACHE_HOME")
+    if cacheHome == "" {
+        cacheHome = filepath.Join(getHomePath(), ".cache")
+    }
+    return filepath.Join(cacheHome, "crumb", "walk.json")
+}
+
+// Only read with Walk.Cache set, otherwise every dir is read each walk
+func loadWalkCache(conf *Config) {
+    walkCache = &dirCache{}
+    if !conf.Walk.Cache {
+        return
+    }
+
+    cache := &dirCache{path: walkCachePath()}
+    if content, err := ioutil.ReadFile(cache.path); err == nil {
+        if err := json.Unmarshal(content, cache); err != nil {
+            fmt.Fprintf(os.Stderr, "crumb: ignoring unreadable walk cache %s\n", cache.path)
+        }
+    }
+    if cache.Dirs == nil || cache.CrumbFileName != conf.CrumbFileName {
+        cache.CrumbFileName = conf.CrumbFileName
+        cache.Dirs = make(map[string]dirListing)
+    }
+    cache.walked = make(map[string]bool)
+    walkCache = cache
+}
+
+func (cache *dirCache) get(dir string) (dirListing, bool) {
+    cache.mu.Lock()
+    defer cache.mu.Unlock()
+    if cache.walked != nil {
+        cache.walked[dir] = true
+    }
+    listing, found := cache.Dirs[dir]
+    return listing, found
+}
+
+// Dirs below root the walk did not reach are gone or no longer walked, so
+// their listings are dropped
+func (cache *dirCache) evictUnwalked(root string) {
+    cache.mu.Lock()
+    defer cache.mu.Unlock()
+    for dir, _ := range cache.Dirs {
+        below := dir == root || strings.HasPrefix(dir, root + string(filepath.Separator))
+        if below && !cache.walked[dir] {
+            delete(cache.Dirs, dir)
+            cache.dirty = true
+        }
+    }
+}
+
+func (cache *dirCache) put(dir string, listing dirListing) {
+    cache.mu.Lock()
+    defer cache.mu.Unlock()
+    if cache.Dirs != nil {
+        cache.Dirs[dir] = listing
+        cache.dirty = true
+    }
+}
+
+// Written to a temporary file first so a concurrent walk never reads half
+// a cache
+func saveWalkCache(root string) {
+    cache := walkCache
+    cache.evictUnwalked(root)
+    if !cache.dirty {
+        return
+    }
+    content, err := json.Marshal(cache)
+    if err == nil {
+        err = os.MkdirAll(filepath.Dir(cache.path), 0755)
+    }
+    if err == nil {
+        err = ioutil.WriteFile(cache.path + ".tmp", content, 0644)
+    }
+    if err == nil {
+        err = os.Rename(cache.path + ".tmp", cache.path)
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "crumb: could not write walk cache %s: %s\n", cache.path, err)
+    }
+    cache.dirty = false
+}
@@ -3,12 +3,11 @@ package crumb
 import (
     "path/filepath"
     "strings"
-    "io/ioutil"
     "os"
-    "log"
     "fmt"
     "bufio"
     "strconv"
+    "log"
 )
 
 func parseSelection(input string, lineNumbers []int) []int {
@@ -46,8 +45,8 @@ func parseSelection(input string, lineNumbers []int) []int {
 
 
 func selectionInteractive(dir string, cmdName string, action func(Crumb) *Crumb) {
-    filter := buildFilters(conf.Filters)
-    sortFns := buildSorts(conf.Sorts)
+    filter := activeFilter(conf)
+    order := buildSorts(conf.Sorts)
 
     crumbFilePath := filepath.Join(dir, conf.CrumbFileName)
     if fileExists(crumbFilePath) {
@@ -57,7 +56,7 @@ func selectionInteractive(dir string, cmdName string, action func(Crumb) *Crumb)
         fmt.Println(header)
 
         crumbLines := strings.Split(fileContent, "\n")
-        crumbs, lineNumbers := getCrumbsFromLines(crumbLines, filter, sortFns, conf)
+        crumbs, lineNumbers := getCrumbsFromLines(crumbLines, filter, order, conf)
 
         printCrumbs(crumbs, true, conf)
 
@@ -73,15 +72,15 @@ func selectionInteractive(dir string, cmdName string, action func(Crumb) *Crumb)
 }
 
 func selection(dir string, input string, action func(Crumb) *Crumb) {
-    filter := buildFilters(conf.Filters)
-    sortFns := buildSorts(conf.Sorts)
+    filter := activeFilter(conf)
+    order := buildSorts(conf.Sorts)
 
     crumbFilePath := filepath.Join(dir, conf.CrumbFileName)
     if fileExists(crumbFilePath) {
         fileContent := readFile(crumbFilePath)
 
         crumbLines := strings.Split(fileContent, "\n")
-        _, lineNumbers := getCrumbsFromLines(crumbLines, filter, sortFns, conf)
+        _, lineNumbers := getCrumbsFromLines(crumbLines, filter, order, conf)
 
         selections := parseSelection(input, lineNumbers)
 
@@ -91,8 +90,10 @@ func selection(dir string, input string, action func(Crumb) *Crumb) {
 }
 
 func interactive(dir string, conf *Config) {
-    helpText := "\n*** Commands ***\n  [l]s  [a]d  [m]a  [u]m  [r]m  [b]a  [w]a  [e]d  [f]i\n> "
+    helpText := "\n*** Commands ***\n  [l]s  [a]d  [m]a  [n]ext  [p]rev  [u]m  [r]m  [b]a  [w]a  [e]d  [f]i  [v]iew\n  [k] mv-up  [j] mv-down  [o] reorder\n> "
     ls(dir, conf)
+    base := currentViewBase(conf)
+    view := ViewConf{}
     reader := bufio.NewReader(os.Stdin)
     for true {
         fmt.Printf(helpText)
@@ -110,6 +111,11 @@ func interactive(dir string, conf *Config) {
                 arg := stack.Pop()
                 if arg == "noFilter" {
                     conf.Filters = []FunctionDesc{}
+                    conf.Where = ""
+                } else if containsString(caseModes, arg) {
+                    searchCaseMode = arg
+                } else if arg == "where" {
+                    conf.Where = andWhere(conf.Where, parseRest(stack))
                 } else {
                     if filter, ok := filterMap[arg]; ok {
                         desc := filter.buildDesc(stack)
@@ -117,12 +123,27 @@ func interactive(dir string, conf *Config) {
                     }
                 }
             }
+        } else if cmd == "v" || cmd == "view" {
+            fmt.Printf("\n*** Views ***\n  %s\nview>> ", strings.Join(viewNames(conf), "  "))
+            input, _ := reader.ReadString('\n')
+            name := input[:len(input) - 1]
+            if name == "" {
+                view = ViewConf{}
+                restoreViewBase(base, conf)
+                ls(dir, conf)
+            } else if found, ok := conf.Views[name]; ok {
+                view = found
+                applyView(view, base, conf)
+                runView(view, dir, conf)
+            } else {
+                fmt.Printf("No view named %s\n", name)
+            }
         } else if cmd == "l" || cmd == "ls" {
-            ls(dir, conf);
+            runView(view, dir, conf);
         } else if cmd == "a" || cmd == "ad" {
             fmt.Printf("ad>> ")
             input, _ := reader.ReadString('\n')
-            ad(dir, input[:len(input) - 1], conf)
+            ad(dir, input[:len(input) - 1], "here", true, conf)
         } else if cmd == "e" || cmd == "ed" {
             setText := func (crumb Crumb) *Crumb {
                 fmt.Printf("text>> ")
@@ -147,7 +168,10 @@ func interactive(dir string, conf *Config) {
                 if input != "\n" {
                     marker := markerFromShortHand(input[:len(input) - 1], conf)
                     if marker == "" {
-                        fmt.Sprintf("Could not evaluate marker %s\n", input[:len(input) - 1])
+                        fmt.Printf("Could not evaluate marker %s\n", input[:len(input) - 1])
+                        return &crumb
+                    } else if !isTransitionAllowed(crumb.marker, marker, conf) {
+                        fmt.Printf("Transition from %s to %s is not allowed\n", crumb.marker, marker)
                         return &crumb
                     } else {
                         crumb.marker = marker
@@ -157,6 +181,16 @@ func interactive(dir string, conf *Config) {
                 return &crumb
             }
             selectionInteractive(dir, "ma", mark)
+        } else if cmd == "n" || cmd == "next" {
+            to := func (marker string) (string, error) {
+                return nextState(marker, conf)
+            }
+            selectionInteractive(dir, "next", transitionAction(to, false, conf))
+        } else if cmd == "p" || cmd == "prev" {
+            to := func (marker string) (string, error) {
+                return prevState(marker, conf)
+            }
+            selectionInteractive(dir, "prev", transitionAction(to, false, conf))
         } else if cmd == "u" || cmd == "um" {
             unMark := func (crumb Crumb) *Crumb {
                 crumb.marker = ""
@@ -168,63 +202,102 @@ func interactive(dir string, conf *Config) {
                 return nil
             }
             selectionInteractive(dir, "rm", rmCrumb)
+        } else if cmd == "k" || cmd == "mv-up" {
+            reorderInteractive(dir, "mv-up", reader, conf)
+        } else if cmd == "j" || cmd == "mv-down" {
+            reorderInteractive(dir, "mv-down", reader, conf)
+        } else if cmd == "o" || cmd == "reorder" {
+            reorderInteractive(dir, "reorder", reader, conf)
         } else if cmd == "b" || cmd == "ba" {
             ba(dir, conf);
         } else if cmd == "w" || cmd == "wa" {
-            wa(dir, conf);
+            wa(dir, walkDepth(conf), conf);
         }
     }
 }
 
-func wa(dir string, conf *Config) {
-    var crumbFilePaths []string
-    maxDepth := 3
+func wa(dir string, depth int, conf *Config) {
+    crumbFilePaths := walkCrumbFiles(dir, depth, conf)
 
-    var walk func(string, int)
-    walk = func (dir string, depth int) {
-        if (depth >= maxDepth) {
-            return
-        }
+    filter := activeFilter(conf)
+    order := buildSorts(conf.Sorts)
+    format := outputFormat("wa", conf)
+    printCrumbFiles(crumbFilePaths, filter, order, format, conf.Walk.Prune, conf)
+}
 
-        files, err := ioutil.ReadDir(dir)
-        if err != nil {
-            return
-        }
-        for _, file := range files {
-            if file.Name() == conf.CrumbFileName {
-                crumbFilePaths = append(crumbFilePaths, filepath.Join(dir, conf.CrumbFileName))
-            } else if file.IsDir() {
-                walk(filepath.Join(dir, file.Name()), depth + 1)
+// The crumb file ad appends to, target is one of here, nearest or root.
+// nearest falls back to the repository root and then dir when no crumb
+// file is found up to StopAt.
+func addTarget(dir string, target string, conf *Config) string {
+    switch target {
+    case "nearest":
+        for _, basePath := range ancestorDirs(dir, conf) {
+            if crumbFilePath := filepath.Join(basePath, conf.CrumbFileName); fileExists(crumbFilePath) {
+                return crumbFilePath
             }
         }
+        if repoRoot := findRepoRoot(canonicalPath(dir)); repoRoot != "" {
+            return filepath.Join(repoRoot, conf.CrumbFileName)
+        }
+    case "root":
+        repoRoot := findRepoRoot(canonicalPath(dir))
+        if repoRoot == "" {
+            log.Fatal(fmt.Sprintf("No repository root above %s", dir))
+        }
+        return filepath.Join(repoRoot, conf.CrumbFileName)
     }
-    walk(filepath.Join(dir), 0)
+    return filepath.Join(dir, conf.CrumbFileName)
+}
 
-    filter := buildFilters(conf.Filters)
-    sortFns := buildSorts(conf.Sorts)
-    for _, crumbFilePath := range crumbFilePaths {
-        printCrumbFile(crumbFilePath, filter, sortFns, conf)
+// Why a new crumb file at crumbFilePath would be unexpected, "" when it is
+// not. Crumb files below another crumb file or inside a repository but not
+// at its root tend to be scattered by accident.
+func unexpectedCrumbFile(crumbFilePath string, conf *Config) string {
+    dir := canonicalPath(filepath.Dir(crumbFilePath))
+    for _, basePath := range ancestorDirs(dir, conf) {
+        if existing := filepath.Join(basePath, conf.CrumbFileName); basePath != dir && fileExists(existing) {
+            return fmt.Sprintf("%s already holds crumbs", existing)
+        }
     }
+    if repoRoot := findRepoRoot(dir); repoRoot != "" && repoRoot != dir {
+        return fmt.Sprintf("it is not at the root of the repository %s", repoRoot)
+    }
+    return ""
 }
 
-func ad(dir string, text string, conf *Config) {
-    if text == "" {
-        text = editWithEditor("")
+// Asks before creating an unexpected crumb file when stdin is a terminal
+// and Add.NewFile is confirm, otherwise warns unless it is create
+func allowNewCrumbFile(crumbFilePath string, conf *Config) bool {
+    reason := unexpectedCrumbFile(crumbFilePath, conf)
+    if reason == "" || conf.Add.NewFile == "create" {
+        return true
     }
-
-    crumbFilePath := filepath.Join(dir, conf.CrumbFileName)
-
-    file, err := os.OpenFile(crumbFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-    if err != nil {
-        log.Fatal(fmt.Sprintf("Unable to access %s for writing", crumbFilePath))
+    if conf.Add.NewFile == "confirm" && isInteractive(os.Stdin) {
+        fmt.Printf("Create a new crumb file %s, %s? [y/N] ", crumbFilePath, reason)
+        answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+        answer = strings.ToLower(strings.TrimSpace(answer))
+        return answer == "y" || answer == "yes"
     }
+    fmt.Fprintf(os.Stderr, "crumb: creating a new crumb file %s, %s\n", crumbFilePath, reason)
+    return true
+}
 
-    if _, err := file.Write([]byte(createCrumbEntry(text) + "\n")); err != nil {
-        log.Fatal(err)
+func ad(dir string, text string, target string, force bool, conf *Config) {
+    if target == "" {
+        target = conf.Add.Target
+    }
+    crumbFilePath := addTarget(dir, target, conf)
+    if !force && !fileExists(crumbFilePath) && !allowNewCrumbFile(crumbFilePath, conf) {
+        fmt.Println("No crumb added")
+        return
     }
 
-    if err := file.Close(); err != nil {
-        log.Fatal(err)
+    if text == "" {
+        text = editWithEditor("")
+    }
+    appendFile(crumbFilePath, createCrumbEntry(text) + "\n")
+    if filepath.Dir(crumbFilePath) != canonicalPath(dir) && filepath.Dir(crumbFilePath) != dir {
+        fmt.Printf("Added to %s\n", crumbFilePath)
     }
 }
 
@@ -240,13 +313,28 @@ func ed(dir string, args string, text string, conf *Config) {
     selection(dir, args, setText)
 }
 
-func ma(dir string, marker string, args string, conf *Config) {
-    mark := func (crumb Crumb) *Crumb {
-        crumb.marker = marker
-        return &crumb
+func ma(dir string, marker string, args string, force bool, conf *Config) {
+    to := func (_ string) (string, error) {
+        return marker, nil
+    }
+
+    selection(dir, args, transitionAction(to, force, conf))
+}
+
+func next(dir string, args string, force bool, conf *Config) {
+    to := func (marker string) (string, error) {
+        return nextState(marker, conf)
+    }
+
+    selection(dir, args, transitionAction(to, force, conf))
+}
+
+func prev(dir string, args string, force bool, conf *Config) {
+    to := func (marker string) (string, error) {
+        return prevState(marker, conf)
     }
 
-    selection(dir, args, mark)
+    selection(dir, args, transitionAction(to, force, conf))
 }
 
 func um(dir string, arg string, conf *Config) {
@@ -261,19 +349,22 @@ func um(dir string, arg string, conf *Config) {
 func ls(dir string, conf *Config) {
     crumbFilePath := filepath.Join(dir, conf.CrumbFileName)
 
-    filter := buildFilters(conf.Filters)
-    sortFns := buildSorts(conf.Sorts)
-    printCrumbFile(crumbFilePath, filter, sortFns, conf)
+    filter := activeFilter(conf)
+    order := buildSorts(conf.Sorts)
+    format := outputFormat("ls", conf)
+    if err := checkGroupBy(conf.GroupBy); err != nil {
+        log.Fatal(err)
+    }
+    printCrumbFile(crumbFilePath, filter, order, format, conf)
 }
 
 func ba(dir string, conf *Config) {
     crumbFilePaths := findCrumbFiles(dir, conf)
 
-    filter := buildFilters(conf.Filters)
-    sortFns := buildSorts(conf.Sorts)
-    for _, crumbFilePath := range crumbFilePaths {
-        printCrumbFile(crumbFilePath, filter, sortFns, conf)
-    }
+    filter := activeFilter(conf)
+    order := buildSorts(conf.Sorts)
+    format := outputFormat("ba", conf)
+    printCrumbFiles(crumbFilePaths, filter, order, format, false, conf)
 }
 
 func rm(dir string, arg string, conf *Config) {
@@ -4,7 +4,12 @@ import (
     "fmt"
     "io/ioutil"
     "log"
+    "os"
     "path/filepath"
+    "reflect"
+    "regexp"
+    "sort"
+    "strings"
 
     "github.com/pelletier/go-toml"
 )
@@ -12,6 +17,12 @@ import (
 type PreSufFix struct {
     Prefix string
     Suffix string
+    Icon string
+    Fg string
+    Bg string
+    Bold bool
+    Italic bool
+    Underline bool
 }
 
 type FunctionDesc struct {
@@ -19,38 +30,475 @@ type FunctionDesc struct {
     Args []string
 }
 
+type MarkdownConf struct {
+    Checkbox map[string]string
+    Import map[string]string
+    Headings string
+}
+
+type IcsConf struct {
+    Status map[string]string
+}
+
+type WorkflowConf struct {
+    States []string
+    Transitions map[string][]string
+    Open []string
+    Closed []string
+}
+
+type WalkConf struct {
+    Ignore []string
+    NoGitignore bool
+    Symlinks string
+    Prune bool
+    Workers int
+    Cache bool
+}
+
+type AddConf struct {
+    Target string
+    NewFile string
+}
+
+type StandupConf struct {
+    Completed []string
+    InProgress []string
+    Blocked []string
+    Window string
+    Scope string
+    Format string
+}
+
+type OutputFormat struct {
+    Crumb string
+    Header string
+    Footer string
+    Group string
+}
+
+type AliasDesc struct {
+    Name string
+    Args []string
+    Description string
+}
+
+type ViewConf struct {
+    Description string
+    Scope string
+    Depth int
+    Filters []FunctionDesc
+    Where string
+    Sorts []FunctionDesc
+    GroupBy string
+    Format string
+    InheritFilters bool
+}
+
 type Config struct {
-    StopAt string
+    StopAt []string
+    BaOrder string
     CrumbFileName string
-    Alias []FunctionDesc
+    Alias []AliasDesc
     Filters []FunctionDesc
+    Where string
     Sorts []FunctionDesc
+    GroupBy string
     Markers map[string]PreSufFix
     UnMarked PreSufFix
     Header PreSufFix
     Selector PreSufFix
+    Highlight PreSufFix
+    Group PreSufFix
+    Color string
+    Format string
+    Formats map[string]OutputFormat
+    CommandFormats map[string]string
+    Views map[string]ViewConf
+    WalkDepth int
+    Walk WalkConf
+    Add AddConf
+    Standup StandupConf
+    Markdown MarkdownConf
+    Ics IcsConf
+    Workflow WorkflowConf
+    Append []string
+    Profile string
+    Profiles map[string]Config
+}
+
+// Sources of the effective settings, keyed by their dotted toml key
+var configSources = make(map[string]string)
+
+const configFileName = ".crumbrc.toml"
+
+var tomlErrorRe = regexp.MustCompile(`^\((\d+), (\d+)\): (.*)`)
+
+func tomlError(path string, err error) string {
+    if matches := tomlErrorRe.FindStringSubmatch(err.Error()); matches != nil {
+        return fmt.Sprintf("%s:%s:%s: %s", path, matches[1], matches[2], matches[3])
+    }
+    return fmt.Sprintf("%s: %s", path, err)
 }
 
-func applyUserConfig(conf *Config) {
-    configFileName := ".crumbrc.toml"
-    crumbrcFilePath := filepath.Join(getHomePath(), configFileName)
+func loadConfigFile(path string) (*Config, *toml.Tree) {
+    content, err := ioutil.ReadFile(path)
+    if err != nil {
+        return nil, nil
+    }
+    return parseConfigFile(path, content)
+}
 
-    content, err := ioutil.ReadFile(crumbrcFilePath)
+func parseConfigFile(path string, content []byte) (*Config, *toml.Tree) {
+    tree, err := toml.LoadBytes(content)
     if err != nil {
+        log.Fatal(tomlError(path, err))
+    }
+
+    // The tree keeps the positions of the file, StopAt is only made a list
+    // in the copy unmarshaled
+    unmarshalTree, _ := toml.LoadBytes(content)
+    listifyStopAt(unmarshalTree)
+    fileConf := &Config{}
+    if err = unmarshalTree.Unmarshal(fileConf); err != nil {
+        log.Fatal(tomlError(path, err))
+    }
+    return fileConf, tree
+}
+
+// StopAt used to take a single dir, a string is read as a list of one
+func listifyStopAt(tree *toml.Tree) {
+    keys := [][]string{{"StopAt"}}
+    if profiles, ok := tree.Get("Profiles").(*toml.Tree); ok {
+        for _, name := range profiles.Keys() {
+            keys = append(keys, []string{"Profiles", name, "StopAt"})
+        }
+    }
+    for _, key := range keys {
+        if stopAt, ok := tree.GetPath(key).(string); ok {
+            tree.SetPath(key, []interface{}{stopAt})
+        }
+    }
+}
+
+// Maps are merged by key when mergeMaps is set and replaced otherwise, lists
+// are replaced. Either is appended to when its key is listed in the files
+// `Append`.
+func mergeConfig(dst reflect.Value, src reflect.Value, tree *toml.Tree, prefix string, source string, mergeMaps bool, appendLists map[string]bool) {
+    for i := 0; i < dst.NumField(); i++ {
+        key := prefix + dst.Type().Field(i).Name
+        if !tree.Has(key) {
+            continue
+        }
+
+        field := dst.Field(i)
+        value := src.Field(i)
+        switch field.Kind() {
+        case reflect.Struct:
+            mergeConfig(field, value, tree, key + ".", source, mergeMaps, appendLists)
+        case reflect.Map:
+            if !(mergeMaps || appendLists[key]) || field.IsNil() {
+                for sourceKey, _ := range configSources {
+                    if strings.HasPrefix(sourceKey, key + ".") {
+                        delete(configSources, sourceKey)
+                    }
+                }
+                field.Set(reflect.MakeMap(field.Type()))
+            }
+            for _, mapKey := range value.MapKeys() {
+                field.SetMapIndex(mapKey, value.MapIndex(mapKey))
+                configSources[key + "." + mapKey.String()] = source
+            }
+        case reflect.Slice:
+            if appendLists[key] && configSources[key] != "" {
+                field.Set(reflect.AppendSlice(field, value))
+                configSources[key] += ", " + source
+            } else {
+                field.Set(value)
+                configSources[key] = source
+            }
+        default:
+            field.Set(value)
+            configSources[key] = source
+        }
+    }
+}
+
+type loadedConfig struct {
+    path string
+    conf *Config
+    tree *toml.Tree
+    mergeMaps bool
+}
+
+// Every config file applied, in order of precedence, so profiles can be
+// applied from each of them
+var loadedConfigs []loadedConfig
+
+// The profile applied on top of loadedConfigs, if any
+var appliedProfile string
+
+func appendListsOf(fileConf *Config) map[string]bool {
+    appendLists := make(map[string]bool)
+    for _, key := range fileConf.Append {
+        appendLists[key] = true
+    }
+    return appendLists
+}
+
+func applyConfigFile(conf *Config, path string, mergeMaps bool) {
+    fileConf, tree := loadConfigFile(path)
+    if fileConf == nil {
         return
     }
 
-    if err = toml.Unmarshal(content, conf); err != nil {
-        fmt.Println(err)
-        log.Fatal(fmt.Sprintf("Invalid %s", configFileName))
+    applyLoadedConfig(conf, loadedConfig{path, fileConf, tree, mergeMaps})
+}
+
+func applyLoadedConfig(conf *Config, loaded loadedConfig) {
+    loadedConfigs = append(loadedConfigs, loaded)
+    mergeConfig(reflect.ValueOf(conf).Elem(), reflect.ValueOf(loaded.conf).Elem(), loaded.tree, "", loaded.path, loaded.mergeMaps, appendListsOf(loaded.conf))
+}
+
+// Validates the config as it would be with the file at path holding content,
+// leaving the loaded config as it is. A file not loaded is validated as if
+// it was the only config.
+func validateConfigWith(path string, content string) []error {
+    savedConfigs, savedSources := loadedConfigs, configSources
+    defer func () {
+        loadedConfigs, configSources = savedConfigs, savedSources
+    }()
+
+    fileConf, tree := parseConfigFile(path, []byte(content))
+    changed := loadedConfig{path, fileConf, tree, false}
+    configs := []loadedConfig{changed}
+    for i, loaded := range savedConfigs {
+        if loaded.path == path {
+            changed.mergeMaps = loaded.mergeMaps
+            configs = append(append(append([]loadedConfig{}, savedConfigs[:i]...), changed), savedConfigs[i + 1:]...)
+            break
+        }
+    }
+
+    loadedConfigs, configSources = nil, make(map[string]string)
+    conf := newDefaultConfig()
+    for _, loaded := range configs {
+        applyLoadedConfig(conf, loaded)
+    }
+    if appliedProfile != "" {
+        applyProfile(conf, appliedProfile)
+    }
+    return validateConfig(conf)
+}
+
+// A profile replaces the maps it sets, such as Markers, unless it lists them
+// in its own `Append`
+func applyProfile(conf *Config, profile string) {
+    found := false
+    for _, loaded := range loadedConfigs {
+        profileTree, ok := loaded.tree.Get("Profiles." + profile).(*toml.Tree)
+        if !ok {
+            continue
+        }
+        found = true
+        profileConf := loaded.conf.Profiles[profile]
+        source := fmt.Sprintf("%s [Profiles.%s]", loaded.path, profile)
+        mergeConfig(reflect.ValueOf(conf).Elem(), reflect.ValueOf(&profileConf).Elem(), profileTree, "", source, false, appendListsOf(&profileConf))
+    }
+    if !found {
+        log.Fatal(fmt.Sprintf("No profile named %s, add it as [Profiles.%s]", profile, profile))
+    }
+}
+
+// Overlays are found from dir and up to `StopAt` and returned farthest first
+func findConfigOverlays(dir string, userConfigPath string, conf *Config) []string {
+    userConfigPath = canonicalPath(userConfigPath)
+    legacyConfigPath := canonicalPath(filepath.Join(getHomePath(), configFileName))
+    var overlays []string
+    for _, basePath := range ancestorDirs(dir, conf) {
+        overlay := filepath.Join(basePath, configFileName)
+        if overlay != userConfigPath && overlay != legacyConfigPath && fileExists(overlay) {
+            overlays = append([]string{overlay}, overlays...)
+        }
+    }
+    return overlays
+}
+
+func xdgConfigPath() string {
+    configHome := os.Getenv("XDG_CONFIG_HOME")
+    if configHome == "" {
+        configHome = filepath.Join(getHomePath(), ".config")
+    }
+    return filepath.Join(configHome, "crumb", "config.toml")
+}
+
+// The user config is the first of `--config`, $CRUMB_CONFIG,
+// $XDG_CONFIG_HOME/crumb/config.toml and $HOME/.crumbrc.toml
+func findUserConfig(configFlag string) string {
+    if configFlag != "" {
+        return configFlag
+    }
+    if path := os.Getenv("CRUMB_CONFIG"); path != "" {
+        return path
+    }
+    if path := xdgConfigPath(); fileExists(path) {
+        return path
+    }
+    return filepath.Join(getHomePath(), configFileName)
+}
+
+// The user config in use, or where it would be
+var userConfigPath string
+
+// Where to write the user config, the one in use if any and otherwise the
+// xdg location
+func writableConfigPath() string {
+    if fileExists(userConfigPath) || userConfigPath != filepath.Join(getHomePath(), configFileName) {
+        return userConfigPath
+    }
+    return xdgConfigPath()
+}
+
+func applyUserConfig(conf *Config, configFlag string, profileFlag string) {
+    userConfigPath = findUserConfig(configFlag)
+    if configFlag != "" && !fileExists(userConfigPath) {
+        log.Fatal(fmt.Sprintf("Config file %s does not exist", userConfigPath))
+    }
+    applyConfigFile(conf, userConfigPath, false)
+
+    // The config is read before args are parsed so overlays follow the
+    // working directory, not the PATH of the command
+    for _, overlay := range findConfigOverlays(getWD(), userConfigPath, conf) {
+        applyConfigFile(conf, overlay, true)
+    }
+
+    profile := profileFlag
+    if profile == "" {
+        profile = os.Getenv("CRUMB_PROFILE")
+    }
+    if profile == "" {
+        profile = conf.Profile
+    }
+    if profile != "" {
+        applyProfile(conf, profile)
+        appliedProfile = profile
+    }
+}
+
+var bareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+    if bareKeyRe.MatchString(key) {
+        return key
+    }
+    return tomlString(key)
+}
+
+func tomlString(str string) string {
+    var quoted strings.Builder
+    quoted.WriteString(`"`)
+    for _, r := range str {
+        switch {
+        case r == '"' || r == '\\':
+            quoted.WriteString(`\` + string(r))
+        case r < 0x20 || r == 0x7f:
+            quoted.WriteString(fmt.Sprintf(`\u%04X`, r))
+        default:
+            quoted.WriteRune(r)
+        }
+    }
+    quoted.WriteString(`"`)
+    return quoted.String()
+}
+
+func explainValue(value reflect.Value) string {
+    switch value.Kind() {
+    case reflect.String:
+        return tomlString(value.String())
+    case reflect.Slice:
+        var values []string
+        for i := 0; i < value.Len(); i++ {
+            values = append(values, explainValue(value.Index(i)))
+        }
+        return "[" + strings.Join(values, ", ") + "]"
+    case reflect.Struct:
+        var fields []string
+        for i := 0; i < value.NumField(); i++ {
+            if !isZeroValue(value.Field(i)) {
+                fields = append(fields, fmt.Sprintf("%s = %s", value.Type().Field(i).Name, explainValue(value.Field(i))))
+            }
+        }
+        return "{" + strings.Join(fields, ", ") + "}"
+    case reflect.Map:
+        var entries []string
+        for _, mapKey := range value.MapKeys() {
+            entries = append(entries, fmt.Sprintf("%s = %s", tomlKey(mapKey.String()), explainValue(value.MapIndex(mapKey))))
+        }
+        sort.Strings(entries)
+        return "{" + strings.Join(entries, ", ") + "}"
+    }
+    return fmt.Sprintf("%v", value.Interface())
+}
+
+func isZeroValue(value reflect.Value) bool {
+    return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
+
+func explainSource(key string) string {
+    if source, found := configSources[key]; found {
+        return source
+    }
+    return "default"
+}
+
+func printConfigLine(key string, value reflect.Value, withSources bool) {
+    if withSources {
+        fmt.Printf("%s = %s\t# %s\n", key, explainValue(value), explainSource(key))
+    } else {
+        fmt.Printf("%s = %s\n", key, explainValue(value))
+    }
+}
+
+// Prints the config as dotted toml keys, optionally with the file each
+// setting came from
+func explainConfig(value reflect.Value, prefix string, withSources bool) {
+    for i := 0; i < value.NumField(); i++ {
+        key := prefix + value.Type().Field(i).Name
+        field := value.Field(i)
+        switch field.Kind() {
+        case reflect.Struct:
+            explainConfig(field, key + ".", withSources)
+        case reflect.Map:
+            var mapKeys []string
+            for _, mapKey := range field.MapKeys() {
+                mapKeys = append(mapKeys, mapKey.String())
+            }
+            sort.Strings(mapKeys)
+            for _, mapKey := range mapKeys {
+                entryKey := key + "." + tomlKey(mapKey)
+                if withSources {
+                    entryKey = key + "." + mapKey
+                }
+                printConfigLine(entryKey, field.MapIndex(reflect.ValueOf(mapKey)), withSources)
+            }
+        default:
+            if !isZeroValue(field) || configSources[key] != "" {
+                printConfigLine(key, field, withSources)
+            }
+        }
     }
 }
 
 func newDefaultConfig() *Config {
     return &Config{
-        StopAt: "/",
+        StopAt: []string{"/"},
+        BaOrder: "nearest",
         CrumbFileName: ".crumb",
         Markers: map[string]PreSufFix{"m": PreSufFix{}},
+        Highlight: PreSufFix{Bold: true, Underline: true},
+        Walk: WalkConf{Ignore: []string{".git", "node_modules"}},
+        Add: AddConf{Target: "here", NewFile: "confirm"},
+        Standup: StandupConf{Window: "yesterday", Scope: "wa", Format: "plain"},
     }
 }
 
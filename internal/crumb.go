@@ -7,6 +7,8 @@ import (
     "strings"
     "log"
     "errors"
+    "sort"
+    "sync"
 )
 
 type Crumb struct {
@@ -43,11 +45,35 @@ func stringFromCrumb(crumb Crumb, conf *Config) string {
     return fmt.Sprintf("%s%s%s%s", modifedDateString, createdDateString, marker, crumb.text)
 }
 
-func makeCrumb(crumbLine string, conf *Config) (Crumb, error) {
+// Like stringFromCrumb but keeps the dates of the crumb as they are
+func rawStringFromCrumb(crumb Crumb) string {
+    var str string
+    if crumb.modifiedDate != nil {
+        str += formatDate(*crumb.modifiedDate) + " "
+    }
+    if crumb.createdDate != nil {
+        str += formatDate(*crumb.createdDate) + " "
+    }
+    if crumb.marker != "" {
+        str += crumb.marker + " "
+    }
+    return str + crumb.text
+}
+
+// The crumb line regexp of each config, compiled on first use. The markers
+// of a config are not changed once crumbs are read with it.
+var crumbLineRes sync.Map
+
+func crumbLineRe(conf *Config) *regexp.Regexp {
+    if re, found := crumbLineRes.Load(conf); found {
+        return re.(*regexp.Regexp)
+    }
+
     var markers []string
     for marker, _ := range conf.Markers {
-        markers = append(markers, marker)
+        markers = append(markers, regexp.QuoteMeta(marker))
     }
+    sort.Strings(markers)
     markersRe := fmt.Sprintf("(?:(%s) )", strings.Join(markers, "|"))
 
     re, err := regexp.Compile(fmt.Sprintf(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} )?(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} )%s?(.*)`, markersRe))
@@ -55,8 +81,12 @@ func makeCrumb(crumbLine string, conf *Config) (Crumb, error) {
         log.Fatal(fmt.Sprintf("Bad `Markers=%s` unable to compile regexp",
                               strings.Join(markers, ", ")))
     }
+    crumbLineRes.Store(conf, re)
+    return re
+}
 
-    matches := re.FindStringSubmatch(crumbLine)
+func makeCrumb(crumbLine string, conf *Config) (Crumb, error) {
+    matches := crumbLineRe(conf).FindStringSubmatch(crumbLine)
     crumb := Crumb{}
 
     if len(matches) == 0 {
@@ -78,6 +108,39 @@ func makeCrumb(crumbLine string, conf *Config) (Crumb, error) {
     return crumb, nil
 }
 
+var tagRe = regexp.MustCompile(`(?:^|\s)#([\w-]+)`)
+
+func crumbTags(crumb Crumb) []string {
+    var tags []string
+    for _, match := range tagRe.FindAllStringSubmatch(crumb.text, -1) {
+        tags = append(tags, match[1])
+    }
+    return tags
+}
+
+var fieldRe = regexp.MustCompile(`(?:^|\s)([A-Za-z][\w-]*):([^\s/][^\s]*)`)
+
+func crumbFields(crumb Crumb) map[string]string {
+    fields := make(map[string]string)
+    for _, match := range fieldRe.FindAllStringSubmatch(crumb.text, -1) {
+        fields[match[1]] = match[2]
+    }
+    return fields
+}
+
+func crumbDue(crumb Crumb) *time.Time {
+    due, found := crumbFields(crumb)["due"]
+    if !found {
+        return nil
+    }
+    for _, layout := range []string{"2006-01-02T15:04", "2006-01-02"} {
+        if date, err := time.Parse(layout, due); err == nil {
+            return &date
+        }
+    }
+    return nil
+}
+
 func createCrumbEntry(text string) string {
     createDate := formatDate(time.Now())
     return fmt.Sprintf("%s %s", createDate, text)
@@ -3,9 +3,17 @@ package crumb
 import (
     "strings"
     "path/filepath"
-    "sort"
+    "log"
+    "fmt"
+    "crypto/sha1"
 )
 
+type fileCrumbs struct {
+    path string
+    crumbs []Crumb
+    ids []string
+}
+
 func crumbsFromFileContent(crumbContent string, conf *Config) []Crumb {
     crumbLines := strings.Split(crumbContent, "\n")
 
@@ -23,41 +31,159 @@ func crumbsFromFileContent(crumbContent string, conf *Config) []Crumb {
     return crumbs
 }
 
+// A StopAt entry is a dir, the walk up stops below it, or the name of a
+// file or dir such as ".git", the walk up then stops at the first dir
+// holding it
+type boundary struct {
+    dir string
+    marker string
+}
+
+func parseBoundaries(conf *Config) []boundary {
+    var boundaries []boundary
+    for _, entry := range conf.StopAt {
+        path := expandPath(entry)
+        if filepath.IsAbs(path) {
+            boundaries = append(boundaries, boundary{dir: canonicalPath(path)})
+        } else {
+            boundaries = append(boundaries, boundary{marker: entry})
+        }
+    }
+    return boundaries
+}
+
+// Dirs from dir and up to the nearest boundary, nearest first. Symlinks are
+// resolved first so the walk up follows the real path.
+func ancestorDirs(dir string, conf *Config) []string {
+    boundaries := parseBoundaries(conf)
+
+    var dirs []string
+    for basePath := canonicalPath(dir); ; basePath = filepath.Dir(basePath) {
+        stop := false
+        for _, b := range boundaries {
+            if b.dir == basePath {
+                return dirs
+            }
+            if b.marker != "" && pathExists(filepath.Join(basePath, b.marker)) {
+                stop = true
+            }
+        }
+        dirs = append(dirs, basePath)
+        if stop || filepath.Dir(basePath) == basePath {
+            return dirs
+        }
+    }
+}
+
+// Crumb files are listed by their real path, so one linked into several of
+// the dirs is only listed once
 func findCrumbFiles(dir string, conf *Config) []string {
     var crumbFilePaths []string
-    for basePath := dir; basePath != conf.StopAt; basePath = filepath.Join(basePath, "..") {
-        crumbFilePath := filepath.Join(basePath, conf.CrumbFileName)
+    seen := make(map[string]bool)
+    for _, basePath := range ancestorDirs(dir, conf) {
+        crumbFilePath := canonicalPath(filepath.Join(basePath, conf.CrumbFileName))
+        if seen[crumbFilePath] {
+            continue
+        }
+        seen[crumbFilePath] = true
         crumbFilePaths = append(crumbFilePaths, crumbFilePath)
     }
 
+    if conf.BaOrder == "top-down" {
+        for i, j := 0, len(crumbFilePaths) - 1; i < j; i, j = i + 1, j - 1 {
+            crumbFilePaths[i], crumbFilePaths[j] = crumbFilePaths[j], crumbFilePaths[i]
+        }
+    }
     return crumbFilePaths
 }
 
-func getCrumbsFromLines(crumbLines []string, filter func(Crumb) bool, sortFns []func(func (int) Crumb) less, conf *Config) ([]Crumb, []int) {
-    var zip []struct{Crumb; int}
+func crumbFilesInScope(scope string, dir string, conf *Config) []string {
+    switch scope {
+    case "ls":
+        return []string{filepath.Join(dir, conf.CrumbFileName)}
+    case "ba":
+        return findCrumbFiles(dir, conf)
+    case "wa":
+        return walkCrumbFiles(dir, walkDepth(conf), conf)
+    }
+    log.Fatal(fmt.Sprintf("Unknown scope %s, expected one of ls, ba or wa", scope))
+    return nil
+}
 
+func readCrumbFiles(crumbFilePaths []string, filter func(Crumb) bool, order compareFn, conf *Config) []fileCrumbs {
+    read := make([]*fileCrumbs, len(crumbFilePaths))
+    forEachParallel(len(crumbFilePaths), walkWorkers(conf), func (i int) {
+        crumbFilePath := crumbFilePaths[i]
+        if fileExists(crumbFilePath) {
+            crumbLines := strings.Split(readFile(crumbFilePath), "\n")
+            crumbs, lineNumbers := getCrumbsFromLines(crumbLines, filter, order, conf)
+
+            idsByLine := crumbIDsByLine(crumbLines, crumbFilePath, conf)
+            var ids []string
+            for _, lineNumber := range lineNumbers {
+                ids = append(ids, idsByLine[lineNumber])
+            }
+            read[i] = &fileCrumbs{path: crumbFilePath, crumbs: crumbs, ids: ids}
+        }
+    })
+
+    var files []fileCrumbs
+    for _, file := range read {
+        if file != nil {
+            files = append(files, *file)
+        }
+    }
+    return files
+}
+
+// Crumbs are identified by their canonical file path, creation date and
+// text so an id survives reordering and reading the file through another
+// path. Identical crumbs are told apart by their order in the file.
+func crumbID(crumbFilePath string, created string, text string, nth int) string {
+    hash := sha1.Sum([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d", crumbFilePath, created, text, nth)))
+    return fmt.Sprintf("%x", hash[:8])
+}
+
+func crumbIDsByLine(crumbLines []string, crumbFilePath string, conf *Config) map[int]string {
+    ids := make(map[int]string)
+    seen := make(map[string]int)
+    canonicalFilePath := canonicalPath(crumbFilePath)
     for lineNumber, crumbLine := range crumbLines {
-        if (crumbLine != "") {
-            if crumb, err := makeCrumb(crumbLine, conf); err == nil {
-                zip = append(zip, struct{Crumb; int}{crumb, lineNumber})
+        if crumbLine == "" {
+            continue
+        }
+        if crumb, err := makeCrumb(crumbLine, conf); err == nil {
+            var created string
+            if crumb.createdDate != nil {
+                created = formatDate(*crumb.createdDate)
             }
+            key := created + "\x00" + crumb.text
+            ids[lineNumber] = crumbID(canonicalFilePath, created, crumb.text, seen[key])
+            seen[key]++
         }
     }
+    return ids
+}
 
-    for _, sortFn := range sortFns {
-        sort.SliceStable(zip, sortFn(func (i int) Crumb {
-            return zip[i].Crumb
-        }))
+func getCrumbsFromLines(crumbLines []string, filter func(Crumb) bool, order compareFn, conf *Config) ([]Crumb, []int) {
+    var items []sortItem
+
+    for lineNumber, crumbLine := range crumbLines {
+        if (crumbLine != "") {
+            if crumb, err := makeCrumb(crumbLine, conf); err == nil && filter(crumb) {
+                items = append(items, sortItem{crumb, lineNumber})
+            }
+        }
     }
 
+    sortItems(items, order)
+
     var crumbs []Crumb
     var lineNumbers []int
 
-    for _, e := range zip {
-        if filter(e.Crumb) {
-            crumbs = append(crumbs, e.Crumb)
-            lineNumbers = append(lineNumbers, e.int)
-        }
+    for _, item := range items {
+        crumbs = append(crumbs, item.crumb)
+        lineNumbers = append(lineNumbers, item.position)
     }
 
     return crumbs, lineNumbers
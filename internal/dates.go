@@ -0,0 +1,136 @@
+package crumb
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// A point in time or, for a day such as "today" or "2026-10-01", the whole
+// day from start up to end
+type timeSpan struct {
+    start time.Time
+    end time.Time
+}
+
+func (span timeSpan) isDay() bool {
+    return span.end.After(span.start)
+}
+
+// Crumb dates are written in local time but parsed without a zone, so now
+// has to be read the same way to compare with them
+func crumbNow() time.Time {
+    now := time.Now()
+    return time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), now.Nanosecond(), time.UTC)
+}
+
+func startOfDay(date time.Time) time.Time {
+    return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
+
+func daySpan(date time.Time) timeSpan {
+    start := startOfDay(date)
+    return timeSpan{start, start.AddDate(0, 0, 1)}
+}
+
+var durationPartRe = regexp.MustCompile(`(\d+)([smhdw])`)
+var durationRe = regexp.MustCompile(`^(\d+[smhdw])+$`)
+
+var durationUnits = map[string]time.Duration{
+    "s": time.Second,
+    "m": time.Minute,
+    "h": time.Hour,
+    "d": 24 * time.Hour,
+    "w": 7 * 24 * time.Hour,
+}
+
+// Durations are one or more counts of s, m, h, d or w such as 90m or 1d12h
+func parseDuration(value string) (time.Duration, error) {
+    if !durationRe.MatchString(value) {
+        return 0, fmt.Errorf("could not parse %q as a duration such as 90m, 3d or 2w", value)
+    }
+    var duration time.Duration
+    for _, part := range durationPartRe.FindAllStringSubmatch(value, -1) {
+        n, _ := strconv.Atoi(part[1])
+        duration += time.Duration(n) * durationUnits[part[2]]
+    }
+    return duration, nil
+}
+
+var weekdays = map[string]time.Weekday{
+    "sunday": time.Sunday,
+    "monday": time.Monday,
+    "tuesday": time.Tuesday,
+    "wednesday": time.Wednesday,
+    "thursday": time.Thursday,
+    "friday": time.Friday,
+    "saturday": time.Saturday,
+}
+
+var timeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02T15:04"}
+
+// Parses a duration back from now such as 90m, 3d or 2w, a date or date and
+// time, "now", "today", "yesterday" or a weekday for its latest occurrence.
+// A leading "since:" reads better in some places and is ignored.
+func parseTimeSpan(value string) (timeSpan, error) {
+    value = strings.TrimPrefix(strings.TrimSpace(value), "since:")
+    now := crumbNow()
+
+    if duration, err := parseDuration(value); err == nil {
+        point := now.Add(-duration)
+        return timeSpan{point, point}, nil
+    }
+
+    switch name := strings.ToLower(value); name {
+    case "now":
+        return timeSpan{now, now}, nil
+    case "today":
+        return daySpan(now), nil
+    case "yesterday":
+        return daySpan(now.AddDate(0, 0, -1)), nil
+    default:
+        if weekday, found := weekdays[name]; found {
+            daysAgo := (int(now.Weekday()) - int(weekday) + 7) % 7
+            return daySpan(now.AddDate(0, 0, -daysAgo)), nil
+        }
+    }
+
+    if date, err := time.Parse("2006-01-02", value); err == nil {
+        return daySpan(date), nil
+    }
+    for _, layout := range timeLayouts {
+        if date, err := time.Parse(layout, value); err == nil {
+            return timeSpan{date, date}, nil
+        }
+    }
+    return timeSpan{}, fmt.Errorf("could not parse %q as a date or duration, expected e.g. 90m, 3d, 2w, 2026-10-01, today, yesterday or monday", value)
+}
+
+// Compares date against span, a day matches anywhere within it and equality
+// with a point in time means the same day
+func compareToSpan(date time.Time, span timeSpan, op string) bool {
+    if !span.isDay() && (op == "=" || op == "!=") {
+        span = daySpan(span.start)
+    }
+    end := span.end
+    if !span.isDay() {
+        end = span.start.Add(time.Nanosecond)
+    }
+    switch op {
+    case "=":
+        return !date.Before(span.start) && date.Before(end)
+    case "!=":
+        return date.Before(span.start) || !date.Before(end)
+    case "<":
+        return date.Before(span.start)
+    case "<=":
+        return date.Before(end)
+    case ">":
+        return !date.Before(end)
+    case ">=":
+        return !date.Before(span.start)
+    }
+    return false
+}
@@ -0,0 +1,154 @@
+package crumb
+
+const defaultConfigContent = `# crumb config, see "crumb help" for where crumb looks for it
+
+# Crumbs are read from and added to this file in each dir
+CrumbFileName = ".crumb"
+
+# "crumb ba" follows the crumbs up to the nearest of these boundaries. A dir
+# ("/", "~", "$HOME/src") stops it below that dir, a name such as ".git"
+# stops it at the first dir holding it. Symlinks are resolved first
+StopAt = ["/"]
+
+# "crumb ba" lists the nearest crumb file first, or the top one with
+# "top-down"
+BaOrder = "nearest"
+
+# Styles are stripped unless stdout is a terminal, "always" or "never"
+# overrides that as does --color and $NO_COLOR
+Color = "auto"
+
+# How many dirs deep "crumb wa" walks, --depth overrides it
+WalkDepth = 3
+
+# An expression every listed crumb has to match on top of Filters, see --where
+# Where = 'not (tag = someday and modified < 4w)'
+
+# Filters applied to every listing, drop them with --noFilter
+[[Filters]]
+Name = "isNot"
+Args = ["done"]
+
+# Sorts are applied in order, the last one has the final say. sortBy takes
+# keys such as ["due", "created:desc"], see --sortBy
+[[Sorts]]
+Name = "sortMarkedOrder"
+Args = ["selected", "todo"]
+
+# Aliases expand into their args, "crumb -t ls" lists crumbs modified today.
+# $1..$N are replaced by the args following the alias and $@ by the rest
+[[Alias]]
+Name = "-t"
+Args = ["--isModifiedWithinH", "24"]
+Description = "Only crumbs modified today"
+
+[[Alias]]
+Name = "today"
+Args = ["ls", "--isModifiedWithinH", "24", "$@"]
+Description = "List crumbs modified today, takes an optional PATH"
+
+# Dirs "crumb wa" skips, globs with a slash match the path below the walked
+# dir. Dirs ignored by a .gitignore are skipped too unless NoGitignore is set.
+# Symlinked dirs are followed with Symlinks = "follow" and Prune leaves out
+# crumb files without a crumb left after filtering. Workers bounds how many
+# dirs and files are read at once, 0 picks one from the number of CPUs.
+# Cache keeps dir listings in $XDG_CACHE_HOME/crumb/walk.json, a listing is
+# read again once its dir is modified
+[Walk]
+Ignore = [".git", "node_modules"]
+Symlinks = "skip"
+Prune = false
+Workers = 0
+Cache = false
+
+# The crumb file "crumb ad" adds to, "here", "nearest" for the nearest crumb
+# file "crumb ba" finds or "root" for the repository root. A new crumb file
+# below another one or away from the repository root is confirmed first,
+# NewFile = "warn" only warns and "create" does neither
+[Add]
+Target = "here"
+NewFile = "confirm"
+
+# "crumb standup" lists crumbs marked Completed since the start of Window,
+# and every crumb marked InProgress or Blocked. Completed defaults to the
+# closed workflow states, or any marker without them. Format is plain,
+# markdown, json or a [Formats] name
+[Standup]
+Completed = []
+InProgress = []
+Blocked = []
+Window = "yesterday"
+Scope = "wa"
+Format = "plain"
+
+# The states a crumb moves through with "crumb next" and "crumb prev", in
+# order. Transitions limits the markers each state may move to, Open and
+# Closed decide which crumbs isOpen and isClosed match and which count as
+# completed by stats, standup and exports
+# [Workflow]
+# States = ["todo", "selected", "done"]
+# Open = ["todo", "selected"]
+# Closed = ["done"]
+#
+# [Workflow.Transitions]
+# todo = ["selected"]
+# selected = ["done", "todo"]
+
+# Markers are the words crumbs can be marked with, "crumb ma todo 1".
+# Prefix and Suffix wrap the crumb text and may hold quoted escape codes,
+# Fg and Bg take a color name, bright-<name>, "0"-"255" or "#rrggbb"
+[Markers.todo]
+Prefix = "  "
+Icon = "⬡"
+Bold = true
+
+[Markers.selected]
+Prefix = "➜ "
+Icon = "⬡"
+Fg = "yellow"
+
+[Markers.done]
+Prefix = "  "
+Icon = "⬢"
+Fg = "magenta"
+
+[UnMarked]
+Prefix = "  "
+
+[Header]
+Fg = "blue"
+Underline = true
+
+[Selector]
+Suffix = ":\t"
+
+# Group headings of --groupBy, e.g. "todo (3)"
+[Group]
+Prefix = "  "
+Bold = true
+
+# Text matched by --contains, --matches and --fuzzy
+[Highlight]
+Bold = true
+Underline = true
+
+# Named output formats for --format, each a template per part of a listing,
+# see "crumb help" for the fields. CommandFormats picks the format a command
+# prints with when --format is not given
+# [Formats.repo]
+# Header = '{{.RepoPath}} ({{.Count}}/{{.Total}})'
+# Crumb = '{{.Styled}} ({{.Age}})'
+# Group = '  {{.Name}} {{.Count}}'
+#
+# [CommandFormats]
+# wa = "repo"
+
+# Views bundle a scope, filters, sorts and a format under a name, run with
+# "crumb view stale". InheritFilters keeps the Filters above on top of the
+# view's own
+# [Views.stale]
+# Description = "Open crumbs nobody touched in two weeks"
+# Scope = "wa"
+# Where = 'isOpen and modified < 2w'
+# GroupBy = "marker"
+`
@@ -0,0 +1,110 @@
+package crumb
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+type exportedCrumb struct {
+    ID string `json:"id"`
+    Path string `json:"path"`
+    Marker string `json:"marker"`
+    Text string `json:"text"`
+    Created string `json:"created"`
+    Modified string `json:"modified"`
+    Due string `json:"due"`
+}
+
+func exportDate(date *time.Time) string {
+    if date == nil {
+        return ""
+    }
+    return formatDate(*date)
+}
+
+func exportedCrumbs(files []fileCrumbs) []exportedCrumb {
+    exported := []exportedCrumb{}
+    for _, file := range files {
+        for i, crumb := range file.crumbs {
+            exported = append(exported, exportedCrumb{
+                ID: file.ids[i],
+                Path: file.path,
+                Marker: crumb.marker,
+                Text: crumb.text,
+                Created: exportDate(crumb.createdDate),
+                Modified: exportDate(crumb.modifiedDate),
+                Due: exportDate(crumbDue(crumb)),
+            })
+        }
+    }
+    return exported
+}
+
+// Unless mapped in Markdown.Checkbox only completed crumbs are checked
+func markdownCheckbox(marker string, conf *Config) string {
+    if checkbox, found := conf.Markdown.Checkbox[marker]; found {
+        return checkbox
+    }
+    if isCompletedMarker(marker, conf) {
+        return "x"
+    }
+    return " "
+}
+
+func exportMarkdown(files []fileCrumbs, conf *Config) {
+    for i, file := range files {
+        if i > 0 {
+            fmt.Println()
+        }
+        fmt.Printf("## %s\n\n", filepath.Join(file.path, ".."))
+        for _, crumb := range file.crumbs {
+            fmt.Printf("- [%s] %s\n", markdownCheckbox(crumb.marker, conf), crumb.text)
+        }
+    }
+}
+
+func exportJSON(files []fileCrumbs) {
+    content, err := json.MarshalIndent(exportedCrumbs(files), "", "  ")
+    if err != nil {
+        log.Fatal(err)
+    }
+    fmt.Println(string(content))
+}
+
+func exportCSV(files []fileCrumbs) {
+    writer := csv.NewWriter(os.Stdout)
+    writer.Write([]string{"id", "path", "marker", "text", "created", "modified", "due"})
+    for _, crumb := range exportedCrumbs(files) {
+        writer.Write([]string{crumb.ID, crumb.Path, crumb.Marker, crumb.Text, crumb.Created, crumb.Modified, crumb.Due})
+    }
+    writer.Flush()
+    if err := writer.Error(); err != nil {
+        log.Fatal(err)
+    }
+}
+
+func export(dir string, scope string, format string, conf *Config) {
+    crumbFilePaths := crumbFilesInScope(scope, dir, conf)
+
+    filter := activeFilter(conf)
+    order := buildSorts(conf.Sorts)
+    files := readCrumbFiles(crumbFilePaths, filter, order, conf)
+
+    switch format {
+    case "md":
+        exportMarkdown(files, conf)
+    case "json":
+        exportJSON(files)
+    case "csv":
+        exportCSV(files)
+    case "ics":
+        exportIcs(files, conf)
+    default:
+        log.Fatal(fmt.Sprintf("Unknown export format %s, expected one of md, json, csv or ics", format))
+    }
+}
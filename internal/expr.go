@@ -0,0 +1,401 @@
+package crumb
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// Expressions combine comparisons and filters from filterMap with and, or,
+// not and parentheses, e.g.
+//
+//    (marker = todo or marker = selected) and created > 1d
+//    not (is(note) and modified < 7d) and text ~ "(?i)release"
+//
+// Dates compare against anything parseTimeSpan takes, a day such as today
+// or 2026-10-01 spans the whole day.
+
+type exprToken struct {
+    kind string
+    value string
+    pos int
+}
+
+type exprError struct {
+    expr string
+    pos int
+    msg string
+}
+
+func (e exprError) Error() string {
+    return fmt.Sprintf("%s at col %d\n    %s\n    %s^", e.msg, e.pos + 1, e.expr, strings.Repeat(" ", e.pos))
+}
+
+var exprTokenRe = regexp.MustCompile(`^(?:(\s+)|(\(|\)|,)|(!=|<=|>=|!~|=|<|>|~)|("(?:[^"\\]|\\.)*"|'[^']*')|([^\s(),=!<>~"']+))`)
+
+func tokenizeExpr(expr string) ([]exprToken, error) {
+    var tokens []exprToken
+    for pos := 0; pos < len(expr); {
+        match := exprTokenRe.FindStringSubmatch(expr[pos:])
+        if match == nil {
+            return nil, exprError{expr, pos, fmt.Sprintf("unexpected %q", expr[pos:pos + 1])}
+        }
+        switch {
+        case match[2] != "":
+            tokens = append(tokens, exprToken{match[2], match[2], pos})
+        case match[3] != "":
+            tokens = append(tokens, exprToken{"op", match[3], pos})
+        case match[4] != "":
+            value := match[4][1:len(match[4]) - 1]
+            if match[4][0] == '"' {
+                unquoted, err := strconv.Unquote(match[4])
+                if err != nil {
+                    return nil, exprError{expr, pos, "invalid string"}
+                }
+                value = unquoted
+            }
+            tokens = append(tokens, exprToken{"string", value, pos})
+        case match[5] != "":
+            kind := "word"
+            if keyword := strings.ToLower(match[5]); keyword == "and" || keyword == "or" || keyword == "not" {
+                kind = keyword
+            }
+            tokens = append(tokens, exprToken{kind, match[5], pos})
+        }
+        pos += len(match[0])
+    }
+    return append(tokens, exprToken{"end", "", len(expr)}), nil
+}
+
+var exprWordRe = regexp.MustCompile(`^[^\s(),=!<>~"']+$`)
+
+// Renames oldMarker where an expression compares marker with it or passes it
+// to a filter, returning the renamed expression and how many were renamed.
+// An expression that does not tokenize is returned as is.
+func renameMarkerInExpr(expr string, oldMarker string, newMarker string) (string, int) {
+    tokens, err := tokenizeExpr(expr)
+    if err != nil {
+        return expr, 0
+    }
+
+    value := newMarker
+    if keyword := strings.ToLower(value); !exprWordRe.MatchString(value) || keyword == "and" || keyword == "or" || keyword == "not" {
+        value = strconv.Quote(value)
+    }
+    var renamed []exprToken
+    inArgs := false
+    for i, token := range tokens {
+        if token.kind == "(" || token.kind == ")" {
+            inArgs = token.kind == "(" && i > 0 && tokens[i - 1].kind == "word"
+            continue
+        }
+        if (token.kind != "word" && token.kind != "string") || token.value != oldMarker {
+            continue
+        }
+        compared := i > 1 && tokens[i - 2].value == "marker" && (tokens[i - 1].value == "=" || tokens[i - 1].value == "!=")
+        if inArgs || compared {
+            renamed = append(renamed, token)
+        }
+    }
+
+    for i := len(renamed) - 1; i >= 0; i-- {
+        pos := renamed[i].pos
+        end := pos + len(exprTokenRe.FindString(expr[pos:]))
+        expr = expr[:pos] + value + expr[end:]
+    }
+    return expr, len(renamed)
+}
+
+type exprParser struct {
+    expr string
+    tokens []exprToken
+    pos int
+}
+
+func (p *exprParser) peek() exprToken {
+    return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+    token := p.tokens[p.pos]
+    if token.kind != "end" {
+        p.pos++
+    }
+    return token
+}
+
+func (p *exprParser) errorAt(token exprToken, msg string) error {
+    return exprError{p.expr, token.pos, msg}
+}
+
+func (p *exprParser) expect(kind string) (exprToken, error) {
+    token := p.next()
+    if token.kind != kind {
+        found := token.value
+        if token.kind == "end" {
+            found = "end of expression"
+        }
+        return token, p.errorAt(token, fmt.Sprintf("expected %q, found %q", kind, found))
+    }
+    return token, nil
+}
+
+func (p *exprParser) parseOr() (filter, error) {
+    left, err := p.parseAnd()
+    if err != nil {
+        return nil, err
+    }
+    for p.peek().kind == "or" {
+        p.next()
+        right, err := p.parseAnd()
+        if err != nil {
+            return nil, err
+        }
+        left = func (l, r filter) filter {
+            return func (crumb Crumb) bool {
+                return l(crumb) || r(crumb)
+            }
+        }(left, right)
+    }
+    return left, nil
+}
+
+func (p *exprParser) parseAnd() (filter, error) {
+    left, err := p.parseNot()
+    if err != nil {
+        return nil, err
+    }
+    for p.peek().kind == "and" {
+        p.next()
+        right, err := p.parseNot()
+        if err != nil {
+            return nil, err
+        }
+        left = func (l, r filter) filter {
+            return func (crumb Crumb) bool {
+                return l(crumb) && r(crumb)
+            }
+        }(left, right)
+    }
+    return left, nil
+}
+
+func (p *exprParser) parseNot() (filter, error) {
+    if p.peek().kind == "not" {
+        p.next()
+        operand, err := p.parseNot()
+        if err != nil {
+            return nil, err
+        }
+        return func (crumb Crumb) bool {
+            return !operand(crumb)
+        }, nil
+    }
+    return p.parsePrimary()
+}
+
+func (p *exprParser) parseValue() (exprToken, error) {
+    token := p.next()
+    if token.kind != "word" && token.kind != "string" {
+        return token, p.errorAt(token, "expected a value")
+    }
+    return token, nil
+}
+
+func (p *exprParser) parsePrimary() (filter, error) {
+    token := p.next()
+    switch token.kind {
+    case "(":
+        inner, err := p.parseOr()
+        if err != nil {
+            return nil, err
+        }
+        if _, err := p.expect(")"); err != nil {
+            return nil, err
+        }
+        return inner, nil
+    case "word":
+    default:
+        if token.kind == "end" {
+            return nil, p.errorAt(token, "unexpected end of expression")
+        }
+        return nil, p.errorAt(token, fmt.Sprintf("unexpected %q", token.value))
+    }
+
+    if p.peek().kind == "op" {
+        op := p.next()
+        value, err := p.parseValue()
+        if err != nil {
+            return nil, err
+        }
+        compiled, err := compileComparison(token.value, op.value, value.value)
+        if err != nil {
+            return nil, p.errorAt(value, err.Error())
+        }
+        return compiled, nil
+    }
+
+    f, found := filterMap[token.value]
+    if !found {
+        var names []string
+        for name, _ := range filterMap {
+            names = append(names, name)
+        }
+        return nil, p.errorAt(token, fmt.Sprintf("unknown filter %q%s", token.value, didYouMean(token.value, names)))
+    }
+
+    var args []string
+    if p.peek().kind == "(" {
+        p.next()
+        for p.peek().kind != ")" {
+            value, err := p.parseValue()
+            if err != nil {
+                return nil, err
+            }
+            args = append(args, value.value)
+            if p.peek().kind != "," {
+                break
+            }
+            p.next()
+        }
+        if _, err := p.expect(")"); err != nil {
+            return nil, err
+        }
+    }
+    if err := f.checkArgs(args); err != nil {
+        return nil, p.errorAt(token, err.Error())
+    }
+    return f.applyFn(args), nil
+}
+
+func compareInts(cmp int, op string) bool {
+    switch op {
+    case "=":
+        return cmp == 0
+    case "!=":
+        return cmp != 0
+    case "<":
+        return cmp < 0
+    case "<=":
+        return cmp <= 0
+    case ">":
+        return cmp > 0
+    case ">=":
+        return cmp >= 0
+    }
+    return false
+}
+
+// Values are compared as dates, then as numbers and last as strings
+func compareValues(a string, b string, op string) bool {
+    if spanA, err := parseTimeSpan(a); err == nil {
+        if spanB, err := parseTimeSpan(b); err == nil {
+            return compareToSpan(spanA.start, spanB, op)
+        }
+    }
+    if numA, err := strconv.ParseFloat(a, 64); err == nil {
+        if numB, err := strconv.ParseFloat(b, 64); err == nil {
+            cmp := 0
+            if numA < numB {
+                cmp = -1
+            } else if numA > numB {
+                cmp = 1
+            }
+            return compareInts(cmp, op)
+        }
+    }
+    return compareInts(strings.Compare(a, b), op)
+}
+
+func compileStringComparison(get func (Crumb) string, op string, value string) (filter, error) {
+    if op == "~" || op == "!~" {
+        re, err := regexp.Compile(value)
+        if err != nil {
+            return nil, fmt.Errorf("invalid regexp: %s", err)
+        }
+        return func (crumb Crumb) bool {
+            return re.MatchString(get(crumb)) == (op == "~")
+        }, nil
+    }
+    return func (crumb Crumb) bool {
+        return compareInts(strings.Compare(get(crumb), value), op)
+    }, nil
+}
+
+func compileComparison(field string, op string, value string) (filter, error) {
+    switch field {
+    case "marker":
+        if op != "=" && op != "!=" && op != "~" && op != "!~" {
+            return nil, fmt.Errorf("marker only compares with =, !=, ~ and !~")
+        }
+        if marker := markerFromShortHand(value, conf); marker != "" && op != "~" && op != "!~" {
+            value = marker
+        }
+        return compileStringComparison(func (crumb Crumb) string {
+            return crumb.marker
+        }, op, value)
+    case "text":
+        return compileStringComparison(func (crumb Crumb) string {
+            return crumb.text
+        }, op, value)
+    case "tag":
+        if op != "=" && op != "!=" {
+            return nil, fmt.Errorf("tag only compares with = and !=")
+        }
+        return func (crumb Crumb) bool {
+            return containsString(crumbTags(crumb), value) == (op == "=")
+        }, nil
+    case "created", "modified":
+        if op == "~" || op == "!~" {
+            return nil, fmt.Errorf("%s does not compare with %s", field, op)
+        }
+        span, err := parseTimeSpan(value)
+        if err != nil {
+            return nil, err
+        }
+        return func (crumb Crumb) bool {
+            crumbDate := crumb.createdDate
+            if field == "modified" && crumb.modifiedDate != nil {
+                crumbDate = crumb.modifiedDate
+            }
+            if crumbDate == nil {
+                return false
+            }
+            return compareToSpan(*crumbDate, span, op)
+        }, nil
+    }
+
+    name := strings.TrimPrefix(field, "field.")
+    if name == field && name != "due" {
+        return nil, fmt.Errorf("unknown field %q, expected marker, text, tag, created, modified, due or field.<NAME>", field)
+    }
+    if op == "~" || op == "!~" {
+        return compileStringComparison(func (crumb Crumb) string {
+            return crumbFields(crumb)[name]
+        }, op, value)
+    }
+    return func (crumb Crumb) bool {
+        fieldValue, found := crumbFields(crumb)[name]
+        if !found {
+            return op == "!="
+        }
+        return compareValues(fieldValue, value, op)
+    }, nil
+}
+
+func compileWhere(expr string) (filter, error) {
+    tokens, err := tokenizeExpr(expr)
+    if err != nil {
+        return nil, err
+    }
+    parser := &exprParser{expr: expr, tokens: tokens}
+    compiled, err := parser.parseOr()
+    if err != nil {
+        return nil, err
+    }
+    if token := parser.peek(); token.kind != "end" {
+        return nil, parser.errorAt(token, fmt.Sprintf("unexpected %q", token.value))
+    }
+    return compiled, nil
+}
@@ -13,6 +13,7 @@ type filter func(Crumb) bool
 type filterFnI interface {
     applyFn([]string) filter
     buildDesc(*SimpleStack) FunctionDesc
+    checkArgs([]string) error
 }
 
 type filterFn struct {
@@ -23,6 +24,10 @@ type filterFn struct {
 type filterArgsFn struct {
     name string
     fn func ([]string) filter
+    check func (string, []string) error
+    // The arg is a search pattern taken whole, commas included, followed
+    // by the case mode of --ignoreCase or --matchCase if given
+    pattern bool
 }
 
 var filterMap = map[string]filterFnI{
@@ -37,10 +42,35 @@ var filterMap = map[string]filterFnI{
     "isCreatedWithinH": filterArgsFn{
         name: "isCreatedWithinH",
         fn: isCreatedWithinH,
+        check: checkWithinArg,
     },
     "isModifiedWithinH": filterArgsFn{
         name: "isModifiedWithinH",
         fn: isModifiedWithinH,
+        check: checkWithinArg,
+    },
+    "isCreatedBetween": filterArgsFn{
+        name: "isCreatedBetween",
+        fn: isCreatedBetween,
+        check: checkTimeArgs(2),
+    },
+    "isModifiedBefore": filterArgsFn{
+        name: "isModifiedBefore",
+        fn: isModifiedBefore,
+        check: checkTimeArgs(1),
+    },
+    "isOlderThan": filterArgsFn{
+        name: "isOlderThan",
+        fn: isOlderThan,
+        check: checkTimeArgs(1),
+    },
+    "isOpen": filterFn{
+        name: "isOpen",
+        fn: isOpen,
+    },
+    "isClosed": filterFn{
+        name: "isClosed",
+        fn: isClosed,
     },
     "isNot": filterArgsFn{
         name: "isNot",
@@ -50,6 +80,24 @@ var filterMap = map[string]filterFnI{
         name: "is",
         fn: is,
     },
+    "contains": filterArgsFn{
+        name: "contains",
+        fn: contains,
+        check: checkSearchArgs,
+        pattern: true,
+    },
+    "matches": filterArgsFn{
+        name: "matches",
+        fn: matches,
+        check: checkSearchArgs,
+        pattern: true,
+    },
+    "fuzzy": filterArgsFn{
+        name: "fuzzy",
+        fn: fuzzy,
+        check: checkSearchArgs,
+        pattern: true,
+    },
 }
 
 func (f filterFn) buildDesc(_ *SimpleStack) FunctionDesc {
@@ -62,12 +110,83 @@ func (f filterArgsFn) buildDesc(s *SimpleStack) FunctionDesc {
     if s.Size() == 0 {
         log.Panic(fmt.Sprintf("Filter %s needs atleast one arg", f.name))
     }
+    if f.pattern {
+        args := []string{s.Pop()}
+        if searchCaseMode != "" {
+            args = append(args, searchCaseMode)
+        }
+        return FunctionDesc{
+            Name: f.name,
+            Args: args,
+        };
+    }
     return FunctionDesc{
         Name: f.name,
         Args: strings.Split(s.Pop(), ","),
     };
 }
 
+func (f filterFn) checkArgs(args []string) error {
+    if len(args) != 0 {
+        return fmt.Errorf("filter %s takes no args, got %d", f.name, len(args))
+    }
+    return nil
+}
+
+func (f filterArgsFn) checkArgs(args []string) error {
+    if len(args) == 0 {
+        return fmt.Errorf("filter %s needs atleast one arg", f.name)
+    }
+    if f.check != nil {
+        return f.check(f.name, args)
+    }
+    return nil
+}
+
+func checkTimeArgs(n int) func (string, []string) error {
+    return func (name string, args []string) error {
+        if len(args) != n {
+            return fmt.Errorf("filter %s takes %d args not %d", name, n, len(args))
+        }
+        for _, arg := range args {
+            if _, err := parseTimeSpan(arg); err != nil {
+                return fmt.Errorf("filter %s %s", name, err)
+            }
+        }
+        return nil
+    }
+}
+
+func checkWithinArg(name string, args []string) error {
+    if len(args) == 1 {
+        args = []string{withinArg(args[0])}
+    }
+    return checkTimeArgs(1)(name, args)
+}
+
+// A bare number is still taken as hours, all the WithinH filters once took
+func withinArg(arg string) string {
+    if _, err := strconv.Atoi(arg); err == nil {
+        return arg + "h"
+    }
+    return arg
+}
+
+func timeSpanArg(arg string) timeSpan {
+    span, err := parseTimeSpan(arg)
+    if err != nil {
+        log.Fatal(err)
+    }
+    return span
+}
+
+func modifiedDate(crumb Crumb) *time.Time {
+    if crumb.modifiedDate != nil {
+        return crumb.modifiedDate
+    }
+    return crumb.createdDate
+}
+
 func (f filterFn) applyFn(_ []string) filter {
     return f.fn()
 }
@@ -79,9 +198,14 @@ func (f filterArgsFn) applyFn(args []string) filter {
 func buildFilters(filterFunctions []FunctionDesc) func (Crumb) bool {
     var filters []func (Crumb) bool
     for _, filterFn := range filterFunctions {
-        if filter, found := filterMap[filterFn.Name]; found {
-            filters = append(filters, filter.applyFn(filterFn.Args))
+        filter, found := filterMap[filterFn.Name]
+        if !found {
+            log.Fatal(fmt.Sprintf("Unknown filter %s", filterFn.Name))
+        }
+        if err := filter.checkArgs(filterFn.Args); err != nil {
+            log.Fatal(err)
         }
+        filters = append(filters, filter.applyFn(filterFn.Args))
     }
     return func (crumb Crumb) bool {
         ret := true
@@ -92,6 +216,42 @@ func buildFilters(filterFunctions []FunctionDesc) func (Crumb) bool {
     }
 }
 
+func andWhere(where string, expr string) string {
+    if where == "" {
+        return expr
+    }
+    return fmt.Sprintf("(%s) and (%s)", where, expr)
+}
+
+// The Filters list and the Where expression apart, each compiled once so
+// their text searches are only registered once
+func activeFilterParts(conf *Config) (filter, filter) {
+    resetTextSearches()
+    return buildFilters(conf.Filters), whereFilter(conf)
+}
+
+// The Filters list and the Where expression must both match
+func activeFilter(conf *Config) filter {
+    filters, where := activeFilterParts(conf)
+    return func (crumb Crumb) bool {
+        return filters(crumb) && where(crumb)
+    }
+}
+
+// Only the Where expression, every crumb passes without one
+func whereFilter(conf *Config) filter {
+    if conf.Where == "" {
+        return func (_ Crumb) bool {
+            return true
+        }
+    }
+    where, err := compileWhere(conf.Where)
+    if err != nil {
+        log.Fatal(fmt.Sprintf("Invalid where expression: %s", err))
+    }
+    return where
+}
+
 func is(args []string) filter {
     return func(crumb Crumb) bool {
         for _, marker := range args {
@@ -104,42 +264,42 @@ func is(args []string) filter {
 }
 
 func isCreatedWithinH(args []string) filter {
-    if len(args) != 1 {
-        log.Fatal(fmt.Sprintf("Filter isCreatedWithinH only excepts 1 arg not %d", len(args[0])))
-    }
-    i, err := strconv.Atoi(args[0])
-    if err != nil {
-        log.Fatal(fmt.Sprintf("Could not parse arg %s to int isCreatedWithinH", args[0]))
+    since := timeSpanArg(withinArg(args[0]))
+    return func (crumb Crumb) bool {
+        return crumb.createdDate != nil && compareToSpan(*crumb.createdDate, since, ">=")
     }
+}
 
+func isModifiedWithinH(args []string) filter {
+    since := timeSpanArg(withinArg(args[0]))
     return func (crumb Crumb) bool {
-        if crumb.createdDate == nil {
-            return false
-        }
-        dT := time.Since(*crumb.createdDate)
-        dH := dT.Hours()
-        return dH <= float64(i)
+        date := modifiedDate(crumb)
+        return date != nil && compareToSpan(*date, since, ">=")
     }
 }
 
-func isModifiedWithinH(args []string) filter {
-    if len(args) != 1 {
-        log.Fatal(fmt.Sprintf("Filter isModifiedWithinH only excepts 1 arg not %d", len(args[0])))
+// Both ends are included, a day as the end covers the whole day
+func isCreatedBetween(args []string) filter {
+    from, to := timeSpanArg(args[0]), timeSpanArg(args[1])
+    return func (crumb Crumb) bool {
+        return crumb.createdDate != nil &&
+            compareToSpan(*crumb.createdDate, from, ">=") &&
+            compareToSpan(*crumb.createdDate, to, "<=")
     }
-    i, err := strconv.Atoi(args[0]); if err != nil {
-        log.Fatal(fmt.Sprintf("Could not parse arg %s to int isModifiedWithinH", args[0]))
+}
+
+func isModifiedBefore(args []string) filter {
+    before := timeSpanArg(args[0])
+    return func (crumb Crumb) bool {
+        date := modifiedDate(crumb)
+        return date != nil && compareToSpan(*date, before, "<")
     }
+}
 
+func isOlderThan(args []string) filter {
+    before := timeSpanArg(args[0])
     return func (crumb Crumb) bool {
-        var date *time.Time
-        if crumb.modifiedDate != nil {
-            date = crumb.modifiedDate
-        } else {
-            date = crumb.createdDate
-        }
-        dT := time.Since(*date)
-        dH := dT.Hours()
-        return dH <= float64(i)
+        return crumb.createdDate != nil && compareToSpan(*crumb.createdDate, before, "<")
     }
 }
 
@@ -165,3 +325,15 @@ func isNotMarked() filter {
         return crumb.marker == ""
     }
 }
+
+func isOpen() filter {
+    return func (crumb Crumb) bool {
+        return isOpenMarker(crumb.marker, conf)
+    }
+}
+
+func isClosed() filter {
+    return func (crumb Crumb) bool {
+        return isClosedMarker(crumb.marker, conf)
+    }
+}
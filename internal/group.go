@@ -0,0 +1,206 @@
+package crumb
+
+import (
+    "fmt"
+    "log"
+    "sort"
+    "strings"
+)
+
+// A crumb as listed along with the file it was read from
+type listedCrumb struct {
+    crumb Crumb
+    data crumbData
+    file fileData
+}
+
+type crumbGroup struct {
+    name string
+    crumbs []listedCrumb
+}
+
+type groupData struct {
+    Key string
+    Name string
+    Count int
+    Styled string
+}
+
+// Each key gives the groups a crumb belongs to, a crumb with several tags
+// is listed under each of them
+var groupKeys = map[string]func (listedCrumb) []string{
+    "marker": func (listed listedCrumb) []string {
+        return []string{listed.crumb.marker}
+    },
+    "created-day": func (listed listedCrumb) []string {
+        return []string{dayString(listed.data.Created)}
+    },
+    "modified-day": func (listed listedCrumb) []string {
+        return []string{dayString(listed.data.Modified)}
+    },
+    "tag": func (listed listedCrumb) []string {
+        if len(listed.data.Tags) == 0 {
+            return []string{""}
+        }
+        return listed.data.Tags
+    },
+    "dir": func (listed listedCrumb) []string {
+        return []string{listed.file.Path}
+    },
+}
+
+var emptyGroupNames = map[string]string{
+    "marker": "unmarked",
+    "created-day": "undated",
+    "modified-day": "undated",
+    "tag": "untagged",
+}
+
+func checkGroupBy(key string) error {
+    if _, found := groupKeys[key]; !found && key != "" {
+        return fmt.Errorf("unknown group %q, expected one of marker, created-day, modified-day, tag or dir", key)
+    }
+    return nil
+}
+
+// Marker groups follow the workflow states, without any the markers given to
+// the last sortMarkedOrder of Sorts
+func markerGroupOrder() func (Crumb) int {
+    if len(conf.Workflow.States) > 0 {
+        return workflowOrder
+    }
+    var markers []string
+    for _, sort := range conf.Sorts {
+        if sort.Name == "sortMarkedOrder" {
+            markers = sort.Args
+        }
+    }
+    return markerOrder(markers)
+}
+
+// Markers follow markerGroupOrder then their names, days go newest
+// first, tags by name and dirs in the order they were listed. Crumbs
+// without a marker, date or tag come last.
+func groupLess(key string, a string, b string) bool {
+    if (a == "") != (b == "") {
+        return b == ""
+    }
+    switch key {
+    case "marker":
+        order := markerGroupOrder()
+        if posA, posB := order(Crumb{marker: a}), order(Crumb{marker: b}); posA != posB {
+            return posA < posB
+        }
+        return a < b
+    case "created-day", "modified-day":
+        return a > b
+    case "tag":
+        return a < b
+    }
+    return false
+}
+
+func groupCrumbs(key string, listed []listedCrumb) []crumbGroup {
+    var groups []crumbGroup
+    positions := make(map[string]int)
+    for _, entry := range listed {
+        for _, name := range groupKeys[key](entry) {
+            if _, found := positions[name]; !found {
+                positions[name] = len(groups)
+                groups = append(groups, crumbGroup{name: name})
+            }
+            groups[positions[name]].crumbs = append(groups[positions[name]].crumbs, entry)
+        }
+    }
+    sort.SliceStable(groups, func (i, j int) bool {
+        return groupLess(key, groups[i].name, groups[j].name)
+    })
+    return groups
+}
+
+func renderGroup(format *compiledFormat, key string, group crumbGroup, conf *Config) string {
+    name := group.name
+    if name == "" {
+        name = emptyGroupNames[key]
+    } else if key == "dir" {
+        name = shortPath(name)
+    }
+    data := groupData{
+        Key: key,
+        Name: name,
+        Count: len(group.crumbs),
+        Styled: preSufFixString(conf.Group, fmt.Sprintf("%s (%d)", name, len(group.crumbs))),
+    }
+    if format.group == nil {
+        return data.Styled
+    }
+    return executeTemplate(format.group, data)
+}
+
+// Prints each group under its heading, withFiles prints the header of a
+// crumb's file whenever it differs from the crumb before it
+func printGroups(groups []crumbGroup, key string, withFiles bool, format *compiledFormat, conf *Config) {
+    for _, group := range groups {
+        fmt.Println(renderGroup(format, key, group, conf))
+        lastFile := ""
+        for _, entry := range group.crumbs {
+            if withFiles && entry.file.File != lastFile {
+                fmt.Println(renderHeader(format, entry.file))
+                lastFile = entry.file.File
+            }
+            fmt.Println(renderCrumb(format, entry.data))
+        }
+    }
+}
+
+func listCrumbFile(crumbFilePath string, filter func (Crumb) bool, order compareFn, conf *Config) (fileData, []listedCrumb) {
+    crumbLines := strings.Split(readFile(crumbFilePath), "\n")
+    crumbs, lineNumbers := getCrumbsFromLines(crumbLines, filter, order, conf)
+    ids := crumbIDsByLine(crumbLines, crumbFilePath, conf)
+
+    file := newFileData(crumbFilePath, len(crumbs), len(ids), conf)
+    var listed []listedCrumb
+    for i, crumb := range crumbs {
+        data := newCrumbData(crumb, i + 1, ids[lineNumbers[i]], file, conf)
+        listed = append(listed, listedCrumb{crumb, data, file})
+    }
+    return file, listed
+}
+
+func printListedFile(file listedFile, format *compiledFormat, conf *Config) {
+    fmt.Println(renderHeader(format, file.file))
+    if conf.GroupBy != "" {
+        printGroups(groupCrumbs(conf.GroupBy, file.crumbs), conf.GroupBy, false, format, conf)
+    } else {
+        for _, entry := range file.crumbs {
+            fmt.Println(renderCrumb(format, entry.data))
+        }
+    }
+    if format.footer != nil {
+        fmt.Println(executeTemplate(format.footer, file.file))
+    }
+}
+
+// Lists the crumbs of every file, ba and wa group them across the files.
+// With prune files without any crumb passing the filter are left out.
+func printCrumbFiles(crumbFilePaths []string, filter func (Crumb) bool, order compareFn, format *compiledFormat, prune bool, conf *Config) {
+    if err := checkGroupBy(conf.GroupBy); err != nil {
+        log.Fatal(err)
+    }
+
+    files := listCrumbFiles(crumbFilePaths, filter, order, conf)
+    if conf.GroupBy != "" {
+        var listed []listedCrumb
+        for _, file := range files {
+            listed = append(listed, file.crumbs...)
+        }
+        printGroups(groupCrumbs(conf.GroupBy, listed), conf.GroupBy, conf.GroupBy != "dir", format, conf)
+        return
+    }
+    if prune {
+        files = pruneListedFiles(files)
+    }
+    for _, file := range files {
+        printListedFile(file, format, conf)
+    }
+}
@@ -0,0 +1,87 @@
+package crumb
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// Unless mapped in Ics.Status only completed crumbs are COMPLETED
+func icsStatus(marker string, conf *Config) string {
+    if status, found := conf.Ics.Status[marker]; found {
+        return strings.ToUpper(status)
+    }
+    if isCompletedMarker(marker, conf) {
+        return "COMPLETED"
+    }
+    return "NEEDS-ACTION"
+}
+
+// Crumb dates hold the local wall clock, ics wants them in utc
+func icsDate(date time.Time) string {
+    local := time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), 0, time.Local)
+    return local.UTC().Format("20060102T150405Z")
+}
+
+func icsEscape(text string) string {
+    replacer := strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\n", `\n`)
+    return replacer.Replace(text)
+}
+
+// Content lines are folded at 75 octets without splitting utf-8 sequences
+func icsLine(line string) string {
+    var folded string
+    limit := 75
+    for len(line) > limit {
+        cut := limit
+        for cut > 0 && !isUtf8Start(line[cut]) {
+            cut--
+        }
+        folded += line[:cut] + "\r\n "
+        line = line[cut:]
+        limit = 74
+    }
+    return folded + line + "\r\n"
+}
+
+func isUtf8Start(b byte) bool {
+    return b & 0xC0 != 0x80
+}
+
+func exportIcs(files []fileCrumbs, conf *Config) {
+    stamp := icsDate(time.Now())
+
+    fmt.Print(icsLine("BEGIN:VCALENDAR"))
+    fmt.Print(icsLine("VERSION:2.0"))
+    fmt.Print(icsLine("PRODID:-//svaante//crumb//EN"))
+    for _, file := range files {
+        for i, crumb := range file.crumbs {
+            fmt.Print(icsLine("BEGIN:VTODO"))
+            fmt.Print(icsLine(fmt.Sprintf("UID:%s@crumb", file.ids[i])))
+            fmt.Print(icsLine("DTSTAMP:" + stamp))
+            fmt.Print(icsLine("SUMMARY:" + icsEscape(crumb.text)))
+            fmt.Print(icsLine("DESCRIPTION:" + icsEscape(file.path)))
+            fmt.Print(icsLine("STATUS:" + icsStatus(crumb.marker, conf)))
+            if crumb.createdDate != nil {
+                fmt.Print(icsLine("CREATED:" + icsDate(*crumb.createdDate)))
+            }
+            if crumb.modifiedDate != nil {
+                fmt.Print(icsLine("LAST-MODIFIED:" + icsDate(*crumb.modifiedDate)))
+            } else if crumb.createdDate != nil {
+                fmt.Print(icsLine("LAST-MODIFIED:" + icsDate(*crumb.createdDate)))
+            }
+            if due := crumbDue(crumb); due != nil {
+                if due.Hour() == 0 && due.Minute() == 0 {
+                    fmt.Print(icsLine("DUE;VALUE=DATE:" + due.Format("20060102")))
+                } else {
+                    fmt.Print(icsLine("DUE:" + icsDate(*due)))
+                }
+            }
+            if crumb.marker != "" {
+                fmt.Print(icsLine("CATEGORIES:" + icsEscape(crumb.marker)))
+            }
+            fmt.Print(icsLine("END:VTODO"))
+        }
+    }
+    fmt.Print(icsLine("END:VCALENDAR"))
+}
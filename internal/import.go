@@ -0,0 +1,135 @@
+package crumb
+
+import (
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+var markdownItemRe = regexp.MustCompile(`^\s*[-*+] \[(.)\] (.*)$`)
+var markdownHeadingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+var slugRe = regexp.MustCompile(`[^\w]+`)
+
+func headingSlug(heading string) string {
+    return strings.Trim(slugRe.ReplaceAllString(strings.ToLower(heading), "-"), "-")
+}
+
+func markerFromCheckbox(checkbox string, conf *Config) string {
+    if marker, found := conf.Markdown.Import[checkbox]; found {
+        return marker
+    }
+
+    if checkbox == " " {
+        return ""
+    }
+
+    var markers []string
+    for marker, _ := range conf.Markers {
+        if markdownCheckbox(marker, conf) == checkbox {
+            markers = append(markers, marker)
+        }
+    }
+    if len(markers) == 0 {
+        log.Fatal(fmt.Sprintf("No marker maps to checkbox [%s], set it in Markdown.Import", checkbox))
+    }
+    sort.Strings(markers)
+    return markers[0]
+}
+
+func existingCrumbTexts(crumbFilePath string, conf *Config) map[string]bool {
+    texts := make(map[string]bool)
+    if fileExists(crumbFilePath) {
+        for _, crumb := range crumbsFromFileContent(readFile(crumbFilePath), conf) {
+            texts[crumb.text] = true
+        }
+    }
+    return texts
+}
+
+func importMarkdown(file string, dir string, headings string, conf *Config) {
+    if headings != "" && headings != "tag" && headings != "dir" {
+        log.Fatal(fmt.Sprintf("Unknown headings mode %s, expected one of tag or dir", headings))
+    }
+
+    lines := strings.Split(readFile(file), "\n")
+
+    var headingStack []string
+    var crumbFilePaths []string
+    entries := make(map[string][]string)
+    texts := make(map[string]map[string]bool)
+    imported, skipped := 0, 0
+
+    for _, line := range lines {
+        if matches := markdownHeadingRe.FindStringSubmatch(line); matches != nil {
+            level := len(matches[1])
+            for len(headingStack) >= level {
+                headingStack = headingStack[:len(headingStack) - 1]
+            }
+            for len(headingStack) < level - 1 {
+                headingStack = append(headingStack, "")
+            }
+            headingStack = append(headingStack, headingSlug(matches[2]))
+            continue
+        }
+
+        matches := markdownItemRe.FindStringSubmatch(line)
+        if matches == nil {
+            continue
+        }
+
+        text := strings.TrimSpace(matches[2])
+        crumbDir := dir
+        for _, slug := range headingStack {
+            if slug == "" {
+                continue
+            }
+            if headings == "tag" {
+                text += " #" + slug
+            } else if headings == "dir" {
+                crumbDir = filepath.Join(crumbDir, slug)
+            }
+        }
+
+        crumbFilePath := filepath.Join(crumbDir, conf.CrumbFileName)
+        if _, found := texts[crumbFilePath]; !found {
+            texts[crumbFilePath] = existingCrumbTexts(crumbFilePath, conf)
+            crumbFilePaths = append(crumbFilePaths, crumbFilePath)
+        }
+        marker := markerFromCheckbox(strings.ToLower(matches[1]), conf)
+        if marker != "" {
+            text = marker + " " + text
+        }
+
+        // The entry is read back the way the crumb file will be, so text
+        // starting with a marker word compares the same on both sides and
+        // a crumb marked since is still found
+        entry := createCrumbEntry(text)
+        crumb, err := makeCrumb(entry, conf)
+        if err != nil {
+            log.Fatal(fmt.Sprintf("Unable to import %s: %s", matches[2], err))
+        }
+        if texts[crumbFilePath][crumb.text] {
+            skipped++
+            continue
+        }
+        texts[crumbFilePath][crumb.text] = true
+        entries[crumbFilePath] = append(entries[crumbFilePath], entry + "\n")
+        imported++
+    }
+
+    for _, crumbFilePath := range crumbFilePaths {
+        if len(entries[crumbFilePath]) == 0 {
+            continue
+        }
+        if err := os.MkdirAll(filepath.Join(crumbFilePath, ".."), 0755); err != nil {
+            log.Fatal(fmt.Sprintf("Unable to create dir for %s", crumbFilePath))
+        }
+        appendFile(crumbFilePath, strings.Join(entries[crumbFilePath], ""))
+    }
+
+    fmt.Printf("Imported %d crumbs, skipped %d already present\n", imported, skipped)
+}
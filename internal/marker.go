@@ -0,0 +1,395 @@
+package crumb
+
+import (
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/pelletier/go-toml"
+)
+
+var tableHeaderRe = regexp.MustCompile(`^\s*\[`)
+
+func markerTableHeaderRe(marker string) *regexp.Regexp {
+    quoted := regexp.QuoteMeta(marker)
+    return regexp.MustCompile(fmt.Sprintf(`^\s*\[\s*Markers\s*\.\s*(?:%s|"%s"|'%s')\s*\]\s*(#.*)?$`, quoted, quoted, quoted))
+}
+
+func readConfigLines(path string) []string {
+    if !fileExists(path) {
+        return []string{}
+    }
+    return strings.Split(strings.TrimRight(readFile(path), "\n"), "\n")
+}
+
+func writeConfigLines(path string, lines []string) {
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        log.Fatal(fmt.Sprintf("Unable to create dir for %s", path))
+    }
+    content := strings.Join(lines, "\n") + "\n"
+    if !fileExists(path) {
+        appendFile(path, content)
+    } else {
+        writeFile(path, content)
+    }
+}
+
+// Returns the line span [start, end) of the markers table, end excluding
+// any blank lines before the next table
+func findMarkerTable(lines []string, marker string) (int, int) {
+    headerRe := markerTableHeaderRe(marker)
+    for start, line := range lines {
+        if !headerRe.MatchString(line) {
+            continue
+        }
+        end := start + 1
+        for end < len(lines) && !tableHeaderRe.MatchString(lines[end]) {
+            end++
+        }
+        for end > start + 1 && strings.TrimSpace(lines[end - 1]) == "" {
+            end--
+        }
+        return start, end
+    }
+    return -1, -1
+}
+
+func markerAdd(path string, marker string, prefix string, suffix string) {
+    lines := readConfigLines(path)
+    if start, _ := findMarkerTable(lines, marker); start != -1 {
+        log.Fatal(fmt.Sprintf("Marker %s already exists in %s", marker, path))
+    }
+
+    if len(lines) > 0 {
+        lines = append(lines, "")
+    }
+    lines = append(lines, fmt.Sprintf("[Markers.%s]", tomlKey(marker)))
+    lines = append(lines, "Prefix = " + tomlString(prefix))
+    if suffix != "" {
+        lines = append(lines, "Suffix = " + tomlString(suffix))
+    }
+    writeConfigLines(path, lines)
+    fmt.Printf("Added marker %s to %s\n", marker, path)
+}
+
+func markerRm(path string, marker string) {
+    lines := readConfigLines(path)
+    start, end := findMarkerTable(lines, marker)
+    if start == -1 {
+        log.Fatal(fmt.Sprintf("Could not find [Markers.%s] in %s", marker, path))
+    }
+
+    for end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+        end++
+    }
+    lines = append(lines[:start], lines[end:]...)
+    writeConfigLines(path, lines)
+    fmt.Printf("Removed marker %s from %s\n", marker, path)
+}
+
+func renameMarkerInCrumbFile(crumbFilePath string, oldMarker string, newMarker string, conf *Config) int {
+    crumbLines := strings.Split(readFile(crumbFilePath), "\n")
+    renamed := 0
+    for i, crumbLine := range crumbLines {
+        if crumbLine == "" {
+            continue
+        }
+        if crumb, err := makeCrumb(crumbLine, conf); err == nil && crumb.marker == oldMarker {
+            crumb.marker = newMarker
+            crumbLines[i] = rawStringFromCrumb(crumb)
+            renamed++
+        }
+    }
+    if renamed > 0 {
+        writeFile(crumbFilePath, strings.Join(crumbLines, "\n"))
+    }
+    return renamed
+}
+
+// Settings whose values name markers, lists of these are renamed in place
+var markerListKeys = map[string]bool{
+    "Args": true,
+    "States": true,
+    "Open": true,
+    "Closed": true,
+    "Completed": true,
+    "InProgress": true,
+    "Blocked": true,
+}
+
+// Tables keyed by marker, the keys of these are renamed
+var markerTableKeys = map[string]bool{
+    "Markers": true,
+    "Transitions": true,
+    "Checkbox": true,
+    "Status": true,
+}
+
+// The tree has no positions for keys of inline tables, these are looked up
+// among the lines of the table holding them
+func findKeyLine(lines []string, tableLine int, key string) int {
+    for i := tableLine; i < len(lines); i++ {
+        if i > tableLine && tableHeaderRe.MatchString(lines[i]) {
+            break
+        }
+        for _, keyRe := range keyRes(key) {
+            if keyRe.MatchString(lines[i]) {
+                return i + 1
+            }
+        }
+    }
+    return 0
+}
+
+// Line numbers of everything in a config naming a marker, found through the
+// parsed tree so arrays spanning lines or using literal strings are found too
+type markerReferences struct {
+    lists []int
+    keys []int
+    values []int
+    wheres []int
+}
+
+func findMarkerReferences(tree *toml.Tree, lines []string, marker string) markerReferences {
+    var refs markerReferences
+    var find func(*toml.Tree, string, int)
+    find = func (tree *toml.Tree, table string, tableLine int) {
+        for _, key := range tree.Keys() {
+            line := tree.GetPosition(key).Line
+            if line == 0 {
+                line = findKeyLine(lines, tableLine, key)
+            }
+            if markerTableKeys[table] && key == marker {
+                refs.keys = append(refs.keys, line)
+            }
+            switch value := tree.Get(key).(type) {
+            case *toml.Tree:
+                find(value, key, line)
+            case []*toml.Tree:
+                for _, sub := range value {
+                    find(sub, key, line)
+                }
+            case []interface{}:
+                if !markerListKeys[key] && table != "Transitions" {
+                    continue
+                }
+                for _, item := range value {
+                    if item == marker {
+                        refs.lists = append(refs.lists, line)
+                        break
+                    }
+                }
+            case string:
+                if table == "Import" && value == marker {
+                    refs.values = append(refs.values, line)
+                } else if _, n := renameMarkerInExpr(value, marker, marker); key == "Where" && n > 0 {
+                    refs.wheres = append(refs.wheres, line)
+                }
+            }
+        }
+    }
+    find(tree, "", 0)
+    return refs
+}
+
+// Returns the end of the toml string starting at line[start]
+func tomlStringEnd(line string, start int) int {
+    quote := line[start]
+    for i := start + 1; i < len(line); i++ {
+        if quote == '"' && line[i] == '\\' {
+            i++
+        } else if line[i] == quote {
+            return i + 1
+        }
+    }
+    return len(line)
+}
+
+func tomlStringValue(token string) string {
+    if strings.HasPrefix(token, "'") {
+        return strings.Trim(token, "'")
+    }
+    if value, err := strconv.Unquote(token); err == nil {
+        return value
+    }
+    return strings.Trim(token, `"`)
+}
+
+// Replaces the string assigned on a line by rename of its value, keeping
+// literal strings literal when the new value allows it
+func renameStringValue(line string, rename func (string) string) (string, error) {
+    i := strings.Index(line, "=") + 1
+    for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+        i++
+    }
+    if i >= len(line) || (line[i] != '"' && line[i] != '\'') {
+        return line, fmt.Errorf("expected a string")
+    }
+    if strings.HasPrefix(line[i:], `"""`) || strings.HasPrefix(line[i:], "'''") {
+        return line, fmt.Errorf("multi-line strings are not renamed")
+    }
+    end := tomlStringEnd(line, i)
+    value := rename(tomlStringValue(line[i:end]))
+    quoted := tomlString(value)
+    if line[i] == '\'' && !strings.ContainsAny(value, "'\n") {
+        quoted = "'" + value + "'"
+    }
+    return line[:i] + quoted + line[end:], nil
+}
+
+// Replaces oldMarker in the array assigned on lines[start], up to the
+// bracket closing it
+func renameInArray(lines []string, start int, oldMarker string, newMarker string) {
+    depth := 0
+    for i := start; i < len(lines); i++ {
+        line := lines[i]
+        var renamed strings.Builder
+        j := 0
+        if i == start {
+            j = strings.Index(line, "=") + 1
+            renamed.WriteString(line[:j])
+        }
+        for j < len(line) {
+            switch c := line[j]; c {
+            case '#':
+                renamed.WriteString(line[j:])
+                j = len(line)
+            case '"', '\'':
+                end := tomlStringEnd(line, j)
+                if token := line[j:end]; tomlStringValue(token) == oldMarker {
+                    renamed.WriteString(tomlString(newMarker))
+                } else {
+                    renamed.WriteString(token)
+                }
+                j = end
+            case '[', ']':
+                if c == '[' {
+                    depth++
+                } else {
+                    depth--
+                }
+                renamed.WriteByte(c)
+                j++
+                if depth == 0 {
+                    lines[i] = renamed.String() + line[j:]
+                    return
+                }
+            default:
+                renamed.WriteByte(c)
+                j++
+            }
+        }
+        lines[i] = renamed.String()
+    }
+}
+
+// A key as the last part of a table header or as a key being assigned,
+// the key itself is the first group
+func keyRes(key string) []*regexp.Regexp {
+    quoted := regexp.QuoteMeta(key)
+    key = fmt.Sprintf(`(%s|"%s"|'%s')`, quoted, quoted, quoted)
+    return []*regexp.Regexp{
+        regexp.MustCompile(`^\s*\[[^\]]*\.\s*` + key + `\s*\]`),
+        regexp.MustCompile(`^\s*` + key + `\s*=`),
+    }
+}
+
+func renameKey(line string, oldMarker string, newMarker string) string {
+    for _, keyRe := range keyRes(oldMarker) {
+        if match := keyRe.FindStringSubmatchIndex(line); match != nil {
+            return line[:match[2]] + tomlKey(newMarker) + line[match[3]:]
+        }
+    }
+    return line
+}
+
+func markerRename(path string, oldMarker string, newMarker string, dir string, conf *Config) {
+    if _, found := conf.Markers[oldMarker]; !found {
+        log.Fatal(fmt.Sprintf("No marker named %s", oldMarker))
+    }
+    if _, found := conf.Markers[newMarker]; found {
+        log.Fatal(fmt.Sprintf("Marker %s already exists", newMarker))
+    }
+
+    lines := readConfigLines(path)
+    tree, err := toml.LoadBytes([]byte(strings.Join(lines, "\n")))
+    if err != nil {
+        log.Fatal(fmt.Sprintf("Unable to parse %s: %s", path, err))
+    }
+    markers, ok := tree.Get("Markers").(*toml.Tree)
+    if !ok || !markers.Has(oldMarker) {
+        log.Fatal(fmt.Sprintf("Could not find [Markers.%s] in %s", oldMarker, path))
+    }
+
+    refs := findMarkerReferences(tree, lines, oldMarker)
+    for _, line := range append(append(append(refs.keys, refs.values...), refs.wheres...), refs.lists...) {
+        if line == 0 {
+            log.Fatal(fmt.Sprintf("Could not find every use of %s in %s, rename it by hand", oldMarker, path))
+        }
+    }
+    for _, line := range refs.keys {
+        lines[line - 1] = renameKey(lines[line - 1], oldMarker, newMarker)
+    }
+    for _, line := range refs.lists {
+        renameInArray(lines, line - 1, oldMarker, newMarker)
+    }
+    renameValue := func (line int, rename func (string) string) {
+        renamedLine, err := renameStringValue(lines[line - 1], rename)
+        if err != nil {
+            log.Fatal(fmt.Sprintf("%s:%d: %s, rename %s by hand", path, line, err, oldMarker))
+        }
+        lines[line - 1] = renamedLine
+    }
+    for _, line := range refs.values {
+        renameValue(line, func (string) string {
+            return newMarker
+        })
+    }
+    for _, line := range refs.wheres {
+        renameValue(line, func (where string) string {
+            renamed, _ := renameMarkerInExpr(where, oldMarker, newMarker)
+            return renamed
+        })
+    }
+
+    if errs := validateConfigWith(path, strings.Join(lines, "\n")); len(errs) > 0 {
+        for _, err := range errs {
+            fmt.Fprintln(os.Stderr, err)
+        }
+        log.Fatal(fmt.Sprintf("Not renaming %s, %s would no longer be valid", oldMarker, path))
+    }
+    writeConfigLines(path, lines)
+
+    renamed, files := 0, 0
+    for _, crumbFilePath := range walkAllCrumbFiles(dir, conf) {
+        if n := renameMarkerInCrumbFile(crumbFilePath, oldMarker, newMarker, conf); n > 0 {
+            renamed += n
+            files++
+        }
+    }
+    fmt.Printf("Renamed marker %s to %s in %s and in %d crumbs across %d files\n", oldMarker, newMarker, path, renamed, files)
+}
+
+func markerList(conf *Config) {
+    var markers []string
+    for marker, _ := range conf.Markers {
+        markers = append(markers, marker)
+    }
+    sort.Strings(markers)
+    for _, marker := range markers {
+        fmt.Printf("%s\t%s\t%s\n", marker, preSufFixString(conf.Markers[marker], marker), explainSource("Markers." + marker))
+    }
+}
+
+func configInit(path string, force bool) {
+    if fileExists(path) && !force {
+        log.Fatal(fmt.Sprintf("%s already exists, use --force to overwrite it", path))
+    }
+    writeConfigLines(path, strings.Split(strings.TrimRight(defaultConfigContent, "\n"), "\n"))
+    fmt.Printf("Wrote default config to %s\n", path)
+}
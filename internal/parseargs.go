@@ -4,10 +4,16 @@ import (
     "fmt"
     "strings"
     "log"
+    "os"
+    "reflect"
+    "strconv"
 )
 
 var conf *Config
 
+// Global flags may also follow the commands listing crumbs
+var globalFlags map[string]CliArg
+
 type CliArg struct {
     do func(*SimpleStack)
     help string
@@ -24,6 +30,78 @@ func parseDir(args *SimpleStack) string  {
     return getWD()
 }
 
+func parseDirArg(args *SimpleStack) string  {
+    if args.Size() > 0 {
+        dir, err := getValidDir(args.Peek())
+        if err == nil {
+            args.Pop()
+            return dir
+        }
+    }
+    return getWD()
+}
+
+// Splits a leading "--flag=value" into "--flag" "value" when known takes
+// the flag, any other arg is left as is so crumb text is never rewritten
+func splitFlagValue(args *SimpleStack, known func (string) bool) {
+    if args.Size() == 0 || !strings.HasPrefix(args.Peek(), "--") {
+        return
+    }
+    if i := strings.Index(args.Peek(), "="); i != -1 && known(args.Peek()[:i]) {
+        arg := args.Pop()
+        args.Prepend([]string{arg[:i], arg[i + 1:]})
+    }
+}
+
+// Consumes the flags of a command and the global flags, stopping at the
+// first arg that is not one. Aliases are never expanded here, only in
+// command position, so a PATH or crumb text named like an alias stays as is.
+func parseCmdFlags(args *SimpleStack, cmdFlags map[string]func(*SimpleStack)) {
+    for args.Size() > 0 && strings.HasPrefix(args.Peek(), "--") {
+        splitFlagValue(args, func (name string) bool {
+            _, isCmdFlag := cmdFlags[name]
+            _, isGlobalFlag := globalFlags[name]
+            return isCmdFlag || isGlobalFlag
+        })
+        if flag, found := cmdFlags[args.Peek()]; found {
+            args.Pop()
+            flag(args)
+        } else if flag, found := globalFlags[args.Peek()]; found {
+            args.Pop()
+            flag.do(args)
+        } else {
+            return
+        }
+    }
+}
+
+// Like parseCmdFlags without the global flags, for commands whose args are
+// free text
+func parseOwnFlags(args *SimpleStack, cmdFlags map[string]func(*SimpleStack)) {
+    for args.Size() > 0 && strings.HasPrefix(args.Peek(), "--") {
+        splitFlagValue(args, func (name string) bool {
+            _, found := cmdFlags[name]
+            return found
+        })
+        flag, found := cmdFlags[args.Peek()]
+        if !found {
+            return
+        }
+        args.Pop()
+        flag(args)
+    }
+}
+
+func parseForce(args *SimpleStack) bool {
+    force := false
+    parseCmdFlags(args, map[string]func(*SimpleStack){
+        "--force": func (_ *SimpleStack) {
+            force = true
+        },
+    })
+    return force
+}
+
 func parseMarker(args *SimpleStack) string {
     if args.Size() == 0 {
             log.Fatal(fmt.Sprintf("Cannot mark without a marker"))
@@ -51,41 +129,270 @@ func printHelp() {
     fmt.Println(fmt.Sprintf(`
 Usage: crumb [OPTIONS] COMMAND
 
+Aliases from the config expand in place of OPTIONS or COMMAND, never among the
+args of a command. $1..$N take the args following the alias and $@ takes the
+rest, so an alias can stand in for flags or commands
+
 COMMAND:
     ls
         Lists crumbs in "DIR/%s"
     wa
-        Follows the bread crumb from "DIR" N deep
+        Follows the bread crumb from "DIR" N deep, skipping dirs matched by "Walk.Ignore"
+        or a .gitignore. Symlinked dirs are followed with Walk.Symlinks = "follow"
     ba
-        Follows the bread crumb from "DIR" and up to "%s"
+        Follows the bread crumb from "DIR" and up to the nearest of "%s" from "StopAt".
+        A dir stops it below that dir, a name such as ".git" at the dir holding it.
+        Nearest crumbs come first unless "BaOrder" or --baOrder is top-down
     ad
-        Add a crumb from CRUMB_BITS in "DIR/%s" does its best to difirentiate CRUMB_BITS from PATH
+        Add a crumb from CRUMB_BITS in "DIR/%s" does its best to difirentiate CRUMB_BITS from PATH.
+        --nearest adds to the nearest crumb file ba finds, --root to the one at the repository
+        root and --here to DIR, the default is "Add.Target". A new crumb file below another one
+        or away from the repository root is confirmed first, --force skips that
     ma
         Mark crumb in "DIR/%s" as done/invalid/archived/... depending on the your metafysical understanding of crumbs
+    next, prev
+        Move crumb in "DIR/%s" to the next/previous state of the marker workflow
     ua
         Unmark crumb in "DIR/%s", what unmark means still depends on the your metafysical understanding of crumbs
     rm
         Remove crumb (eat?) in "DIR/%s"
+    mv-up, mv-down, reorder
+        Move the selected crumbs one line up or down in the crumb file, or to position N
+        among the listed crumbs. Crumbs are selected and moved in file order, the order
+        printed after each move and listed with --sortManual whatever the "Sorts"
+    sort
+        List crumbs as sorted, --write stores that order in the crumb file
+    view, views
+        Run the named view from the config's "Views" or list them
+    export
+        Export crumbs in scope ls/ba/wa as md, json, csv or ics
+    stats
+        Count crumbs in scope ls/ba/wa per marker and dir, crumbs created and completed
+        per week since --since (30d), and the age of open crumbs. Completed crumbs are
+        those in a "Workflow.Closed" state, or any marked crumb without one
+    log
+        Timeline of crumbs created and modified in scope ls/ba/wa (wa) since --since (7d),
+        by day or one line each with --oneline. --author NAME keeps crumbs with the field
+        author:NAME, only the last modification of a crumb is known
+    standup
+        What was completed since --since ("Standup.Window"), is in progress and is blocked,
+        by the markers of "Standup". --format takes plain, markdown, json or a format of
+        "Formats", its Group template renders the section headings. "Filters" do not
+        hide completed crumbs, --where does
+    config
+        Inspect the effective config, --explain shows which file each setting came from,
+        show prints it as toml, init writes a commented default and check validates
+        every config file read
+    marker
+        Add, remove, rename or list markers in the config, rename also renames the
+        marker of every crumb found under "PATH" at any depth, skipping only .git and
+        node_modules, and refuses when the renamed config would not validate
+    import
+        Import a markdown checklist as crumbs in "DIR/%s", skipping crumbs already present
     help
         prints this
 
-crumb sports a config file at "$HOME/.crumbrc.json"`,
+OPTIONS:
+    --config <FILE>
+        Read the config from FILE, has to come first
+    --profile <NAME>
+        Apply the config under [Profiles.NAME], has to come first
+    --color auto|always|never
+        Style output, auto styles it only when stdout is a terminal and $NO_COLOR is unset
+    --format <NAME|TEMPLATE>
+        Print ls/ba/wa with the named format from "Formats" or with an inline crumb
+        template such as '{{.Index}} {{.Marker}} {{.Text}} ({{.Age}})'
+    --where <EXPR>
+        Only show crumbs matching EXPR, on top of "Filters" and "Where" from the config.
+        EXPR combines filters and comparisons with and, or, not and parentheses, e.g.
+        'is(todo) and not (tag = later or created < 2w)'. Comparisons take marker,
+        text, tag, created, modified, due or field.NAME with = != < <= > >= ~ !~, where
+        ~ matches a regexp and dates compare against a date or a duration back from now
+    --noFilter
+        Drop "Filters" and "Where" from the config
+    --contains, --matches, --fuzzy <PATTERN>
+        Only show crumbs whose text contains PATTERN, matches it as a regexp or fuzzy
+        matches it. PATTERN is taken whole, commas included
+    --ignoreCase, --matchCase, --smartCase
+        Case mode of the search filters given after it, smartCase ignores case unless
+        the pattern has an upper case letter
+    --sortBy <KEY[:asc|:desc],...>
+        Sort by each KEY in turn, later keys breaking ties of earlier ones. KEY is one of
+        created, modified, due, text, marker, order=A|B, workflow, position, score or
+        field.NAME, crumbs without a due date or field go last
+    --noSort
+        Drop "Sorts" from the config, keeping the order of the file
+    --depth <N>
+        Walk N dirs deep for wa, defaults to "WalkDepth" or 3
+    --prune
+        Leave out crumb files of wa without any crumb left after filtering
+    --noCache
+        Read every dir for wa even with Walk.Cache set
+    --baOrder nearest|top-down
+        List the crumb files of ba nearest first or from the top down
+    --groupBy marker|created-day|modified-day|tag|dir
+        List crumbs under a heading per group with its count, per file for ls and
+        across every file for ba and wa. Headings are styled by "Group" or by the
+        Group template of a format, markers follow the workflow states or, without
+        any, the order given to sortMarkedOrder in "Sorts"
+
+Time filters such as --isModifiedWithinH, --isOlderThan, --isModifiedBefore and
+--isCreatedBetween FROM,TO take a duration back from now (90m, 3d, 2w, 1d12h), a
+date (2026-10-01, "2026-10-01 12:00"), today, yesterday or a weekday for its
+latest occurrence, optionally written as since:2026-10-01. A bare number is hours
+
+crumb sports a config file at "--config", "$CRUMB_CONFIG",
+"$XDG_CONFIG_HOME/crumb/config.toml" or "$HOME/.crumbrc.toml", the first found
+is used. It is overlaid by any ".crumbrc.toml" found from the working directory
+and up to "%s", the nearest file taking precedence, whatever PATH a command is
+given. Markers are merged by name, lists are
+replaced unless named in the files "Append" list. A profile is picked by
+"--profile", "$CRUMB_PROFILE" or the "Profile" key and is applied last, its
+Markers and other tables replacing those before it unless named in its "Append"`,
         conf.CrumbFileName,
-        conf.StopAt,
+        strings.Join(conf.StopAt, `", "`),
         conf.CrumbFileName,
         conf.CrumbFileName,
         conf.CrumbFileName,
-        conf.CrumbFileName))
+        conf.CrumbFileName,
+        conf.CrumbFileName,
+        conf.CrumbFileName,
+        strings.Join(conf.StopAt, `", "`)))
+    printAliases(conf)
+}
+
+// `--config` and `--profile` decide which config is read so they are taken
+// from the head of args before anything else is parsed
+func parseConfigFlags(args []string) (string, string, []string) {
+    var configFlag, profileFlag string
+    stack := NewSimpleStack(args)
+    for {
+        splitFlagValue(stack, func (name string) bool {
+            return name == "--config" || name == "--profile"
+        })
+        if stack.Size() < 2 {
+            break
+        }
+        if stack.Peek() == "--config" {
+            stack.Pop()
+            configFlag = stack.Pop()
+        } else if stack.Peek() == "--profile" {
+            stack.Pop()
+            profileFlag = stack.Pop()
+        } else {
+            break
+        }
+    }
+    return configFlag, profileFlag, stack.Empty()
 }
 
 func Parse(args []string) {
+    configFlag, profileFlag, args := parseConfigFlags(args)
     conf = newDefaultConfig()
-    applyUserConfig(conf)
+    applyUserConfig(conf, configFlag, profileFlag)
+    if !checkConfig(conf) {
+        os.Exit(1)
+    }
+    if err := setupColor("", conf); err != nil {
+        log.Fatal(err)
+    }
 
     flags := (map[string]CliArg{
+        "--config": CliArg{
+            do: func (_ *SimpleStack) {
+                log.Fatal("--config has to be given before any other argument")
+            },
+            help: "--config <FILE>",
+        },
+        "--profile": CliArg{
+            do: func (_ *SimpleStack) {
+                log.Fatal("--profile has to be given before any other argument")
+            },
+            help: "--profile <NAME>",
+        },
+        "--color": CliArg{
+            do: func (args *SimpleStack) {
+                if err := setupColor(parseString(args), conf); err != nil {
+                    log.Fatal(err)
+                }
+            },
+            help: "--color auto|always|never",
+        },
+        "--format": CliArg{
+            do: func (args *SimpleStack) {
+                formatOverride = parseString(args)
+            },
+            help: "--format <NAME|TEMPLATE>",
+        },
+        "--where": CliArg{
+            do: func (args *SimpleStack) {
+                conf.Where = andWhere(conf.Where, parseString(args))
+            },
+            help: "--where <EXPR>",
+        },
+        "--groupBy": CliArg{
+            do: func (args *SimpleStack) {
+                conf.GroupBy = parseString(args)
+                if err := checkGroupBy(conf.GroupBy); err != nil {
+                    log.Fatal(err)
+                }
+            },
+            help: "--groupBy <KEY>",
+        },
+        "--depth": CliArg{
+            do: func (args *SimpleStack) {
+                depth, err := strconv.Atoi(parseString(args))
+                if err != nil || depth < 1 {
+                    log.Fatal("--depth needs a positive whole number")
+                }
+                conf.WalkDepth = depth
+            },
+            help: "--depth <N>",
+        },
+        "--baOrder": CliArg{
+            do: func (args *SimpleStack) {
+                order := parseString(args)
+                if order != "nearest" && order != "top-down" {
+                    log.Fatal(fmt.Sprintf("Unknown ba order %s, expected nearest or top-down", order))
+                }
+                conf.BaOrder = order
+            },
+            help: "--baOrder nearest|top-down",
+        },
+        "--prune": CliArg{
+            do: func (_ *SimpleStack) {
+                conf.Walk.Prune = true
+            },
+            help: "--prune",
+        },
+        "--noCache": CliArg{
+            do: func (_ *SimpleStack) {
+                conf.Walk.Cache = false
+            },
+            help: "--noCache",
+        },
+        "--ignoreCase": CliArg{
+            do: func (_ *SimpleStack) {
+                searchCaseMode = "ignoreCase"
+            },
+            help: "--ignoreCase",
+        },
+        "--matchCase": CliArg{
+            do: func (_ *SimpleStack) {
+                searchCaseMode = "matchCase"
+            },
+            help: "--matchCase",
+        },
+        "--smartCase": CliArg{
+            do: func (_ *SimpleStack) {
+                searchCaseMode = "smartCase"
+            },
+            help: "--smartCase",
+        },
         "--noFilter": CliArg{
             do: func (_ *SimpleStack) {
                 conf.Filters = []FunctionDesc{}
+                conf.Where = ""
             },
             help: "",
         },
@@ -121,14 +428,17 @@ func Parse(args []string) {
         }
     }
 
+    globalFlags = flags
+
+    aliases := make(map[string]CliArg)
     for _, alias := range conf.Alias {
-        flags[alias.Name] = CliArg{
-            do: func (aliasArgs []string) func (args *SimpleStack) {
+        aliases[alias.Name] = CliArg{
+            do: func (alias AliasDesc) func (args *SimpleStack) {
                     return func (args *SimpleStack) {
-                        args.Prepend(aliasArgs)
+                        args.Prepend(expandAlias(alias, args))
                     }
-                }(alias.Args),
-            help: "",
+                }(alias),
+            help: aliasHelp(alias),
         }
     }
 
@@ -136,32 +446,53 @@ func Parse(args []string) {
     cmds = map[string]CliArg{
         "ls": {
             do: func (args *SimpleStack) {
-                dir := parseDir(args)
+                parseCmdFlags(args, nil)
+                dir := parseDirArg(args)
+                parseCmdFlags(args, nil)
                 ls(dir, conf)
             },
             help: "ls [PATH]",
         },
         "wa": {
             do: func (args *SimpleStack) {
-                dir := parseDir(args)
-                wa(dir, conf)
+                parseCmdFlags(args, nil)
+                dir := parseDirArg(args)
+                parseCmdFlags(args, nil)
+                wa(dir, walkDepth(conf), conf)
             },
             help: "wa [PATH]",
         },
         "ba": {
             do: func (args *SimpleStack) {
-                dir := parseDir(args)
+                parseCmdFlags(args, nil)
+                dir := parseDirArg(args)
+                parseCmdFlags(args, nil)
                 ba(dir, conf)
             },
             help: "ba [PATH]",
         },
         "ad": {
             do: func (args *SimpleStack) {
+                target, force := "", false
+                parseOwnFlags(args, map[string]func(*SimpleStack){
+                    "--here": func (_ *SimpleStack) {
+                        target = "here"
+                    },
+                    "--nearest": func (_ *SimpleStack) {
+                        target = "nearest"
+                    },
+                    "--root": func (_ *SimpleStack) {
+                        target = "root"
+                    },
+                    "--force": func (_ *SimpleStack) {
+                        force = true
+                    },
+                })
                 dir := parseDir(args)
                 text := parseRest(args)
-                ad(dir, text, conf)
+                ad(dir, text, target, force, conf)
             },
-            help: "add [PATH] [...CRUMB_BITS]",
+            help: "add [--here|--nearest|--root] [--force] [PATH] [...CRUMB_BITS]",
         },
         "rm": {
             do: func (args *SimpleStack) {
@@ -173,12 +504,31 @@ func Parse(args []string) {
         },
         "ma": {
             do: func (args *SimpleStack) {
+                force := parseForce(args)
                 dir := parseDir(args)
                 marker := parseMarker(args)
                 selection := parseRest(args)
-                ma(dir, marker, selection, conf)
+                ma(dir, marker, selection, force, conf)
             },
-            help: "ma [PATH] <marker> [...CRUMB_SELECTION]",
+            help: "ma [--force] [PATH] <marker> [...CRUMB_SELECTION]",
+        },
+        "next": {
+            do: func (args *SimpleStack) {
+                force := parseForce(args)
+                dir := parseDir(args)
+                selection := parseRest(args)
+                next(dir, selection, force, conf)
+            },
+            help: "next [--force] [PATH] [...CRUMB_SELECTION]",
+        },
+        "prev": {
+            do: func (args *SimpleStack) {
+                force := parseForce(args)
+                dir := parseDir(args)
+                selection := parseRest(args)
+                prev(dir, selection, force, conf)
+            },
+            help: "prev [--force] [PATH] [...CRUMB_SELECTION]",
         },
         "um": {
             do: func (args *SimpleStack) {
@@ -197,6 +547,283 @@ func Parse(args []string) {
             },
             help: "ed [PATH] <...CRUMB_SELECTION> [...CRUMB_BITS]",
         },
+        "mv-up": {
+            do: func (args *SimpleStack) {
+                dir := parseDir(args)
+                selection := parseRest(args)
+                reorderCrumbs(dir, selection, moveUp, conf)
+            },
+            help: "mv-up [PATH] [...CRUMB_SELECTION]",
+        },
+        "mv-down": {
+            do: func (args *SimpleStack) {
+                dir := parseDir(args)
+                selection := parseRest(args)
+                reorderCrumbs(dir, selection, moveDown, conf)
+            },
+            help: "mv-down [PATH] [...CRUMB_SELECTION]",
+        },
+        "reorder": {
+            do: func (args *SimpleStack) {
+                var to string
+                flags := map[string]func(*SimpleStack){
+                    "--to": func (args *SimpleStack) {
+                        to = parseString(args)
+                    },
+                }
+                parseCmdFlags(args, flags)
+                dir := parseDir(args)
+                var selection []string
+                for parseCmdFlags(args, flags); args.Size() > 0; parseCmdFlags(args, flags) {
+                    if strings.HasPrefix(args.Peek(), "--") {
+                        log.Fatal(fmt.Sprintf("Unknown flag %s for reorder", args.Peek()))
+                    }
+                    selection = append(selection, args.Pop())
+                }
+                n, err := strconv.Atoi(to)
+                if err != nil {
+                    log.Fatal("reorder needs a position as --to <N>")
+                }
+                reorderCrumbs(dir, strings.Join(selection, " "), moveTo(n), conf)
+            },
+            help: "reorder [PATH] <...CRUMB_SELECTION> --to <N>",
+        },
+        "sort": {
+            do: func (args *SimpleStack) {
+                write := false
+                flags := map[string]func(*SimpleStack){
+                    "--write": func (_ *SimpleStack) {
+                        write = true
+                    },
+                }
+                parseCmdFlags(args, flags)
+                dir := parseDirArg(args)
+                parseCmdFlags(args, flags)
+                if write {
+                    sortWrite(dir, conf)
+                } else {
+                    ls(dir, conf)
+                }
+            },
+            help: "sort [PATH] [--sortBy <KEYS>] [--write]",
+        },
+        "view": {
+            do: func (args *SimpleStack) {
+                view := findView(parseString(args), conf)
+                applyView(view, currentViewBase(conf), conf)
+                parseCmdFlags(args, nil)
+                dir := parseDirArg(args)
+                parseCmdFlags(args, nil)
+                runView(view, dir, conf)
+            },
+            help: "view <NAME> [PATH]",
+        },
+        "views": {
+            do: func (args *SimpleStack) {
+                printViews(conf)
+            },
+            help: "views",
+        },
+        "export": {
+            do: func (args *SimpleStack) {
+                scope := "ls"
+                format := "md"
+                exportFlags := map[string]func(*SimpleStack){
+                    "--scope": func (args *SimpleStack) {
+                        scope = parseString(args)
+                    },
+                    "--format": func (args *SimpleStack) {
+                        format = parseString(args)
+                    },
+                }
+                parseCmdFlags(args, exportFlags)
+                dir := parseDirArg(args)
+                parseCmdFlags(args, exportFlags)
+                export(dir, scope, format, conf)
+            },
+            help: "export [PATH] [--scope ls|ba|wa] [--format md|json|csv|ics]",
+        },
+        "stats": {
+            do: func (args *SimpleStack) {
+                scope := "ls"
+                since := "30d"
+                oldest := 5
+                asJSON := false
+                statsFlags := map[string]func(*SimpleStack){
+                    "--scope": func (args *SimpleStack) {
+                        scope = parseString(args)
+                    },
+                    "--since": func (args *SimpleStack) {
+                        since = parseString(args)
+                    },
+                    "--oldest": func (args *SimpleStack) {
+                        n, err := strconv.Atoi(parseString(args))
+                        if err != nil || n < 0 {
+                            log.Fatal("--oldest needs a whole number")
+                        }
+                        oldest = n
+                    },
+                    "--json": func (_ *SimpleStack) {
+                        asJSON = true
+                    },
+                }
+                parseCmdFlags(args, statsFlags)
+                dir := parseDirArg(args)
+                parseCmdFlags(args, statsFlags)
+                stats(dir, scope, since, oldest, asJSON, conf)
+            },
+            help: "stats [PATH] [--scope ls|ba|wa] [--since 30d] [--oldest N] [--json]",
+        },
+        "log": {
+            do: func (args *SimpleStack) {
+                scope := "wa"
+                since := "7d"
+                author := ""
+                oneline := false
+                logFlags := map[string]func(*SimpleStack){
+                    "--scope": func (args *SimpleStack) {
+                        scope = parseString(args)
+                    },
+                    "--since": func (args *SimpleStack) {
+                        since = parseString(args)
+                    },
+                    "--author": func (args *SimpleStack) {
+                        author = parseString(args)
+                    },
+                    "--oneline": func (_ *SimpleStack) {
+                        oneline = true
+                    },
+                }
+                parseCmdFlags(args, logFlags)
+                dir := parseDirArg(args)
+                parseCmdFlags(args, logFlags)
+                timeline(dir, scope, since, author, oneline, conf)
+            },
+            help: "log [PATH] [--scope ls|ba|wa] [--since 7d] [--author NAME] [--oneline]",
+        },
+        "standup": {
+            do: func (args *SimpleStack) {
+                scope := conf.Standup.Scope
+                since := conf.Standup.Window
+                format := ""
+                standupFlags := map[string]func(*SimpleStack){
+                    "--scope": func (args *SimpleStack) {
+                        scope = parseString(args)
+                    },
+                    "--since": func (args *SimpleStack) {
+                        since = parseString(args)
+                    },
+                    "--format": func (args *SimpleStack) {
+                        format = parseString(args)
+                    },
+                }
+                parseCmdFlags(args, standupFlags)
+                dir := parseDirArg(args)
+                parseCmdFlags(args, standupFlags)
+                for _, name := range []string{formatOverride, conf.CommandFormats["standup"], conf.Standup.Format, "plain"} {
+                    if format == "" {
+                        format = name
+                    }
+                }
+                standup(dir, scope, since, format, conf)
+            },
+            help: "standup [PATH] [--scope ls|ba|wa] [--since yesterday] [--format plain|markdown|json|NAME]",
+        },
+        "import": {
+            do: func (args *SimpleStack) {
+                format := "md"
+                headings := conf.Markdown.Headings
+                parseCmdFlags(args, map[string]func(*SimpleStack){
+                    "--format": func (args *SimpleStack) {
+                        format = parseString(args)
+                    },
+                    "--headings": func (args *SimpleStack) {
+                        headings = parseString(args)
+                    },
+                })
+                if format != "md" {
+                    log.Fatal(fmt.Sprintf("Unknown import format %s, expected md", format))
+                }
+                file := parseString(args)
+                dir := parseDirArg(args)
+                importMarkdown(file, dir, headings, conf)
+            },
+            help: "import [--format md] [--headings tag|dir] <FILE> [DEST_DIR]",
+        },
+        "config": {
+            do: func (args *SimpleStack) {
+                explain := false
+                force := false
+                parseCmdFlags(args, map[string]func(*SimpleStack){
+                    "--explain": func (_ *SimpleStack) {
+                        explain = true
+                    },
+                    "--force": func (_ *SimpleStack) {
+                        force = true
+                    },
+                })
+                var subCmd string
+                if args.Size() > 0 {
+                    subCmd = args.Pop()
+                }
+                if explain {
+                    explainConfig(reflect.ValueOf(conf).Elem(), "", true)
+                } else if subCmd == "show" {
+                    explainConfig(reflect.ValueOf(conf).Elem(), "", false)
+                } else if subCmd == "init" {
+                    configInit(writableConfigPath(), force)
+                } else if subCmd == "check" {
+                    if !checkConfig(conf) {
+                        os.Exit(1)
+                    }
+                    fmt.Println("Config is valid")
+                } else {
+                    fmt.Println(cmds["config"].help)
+                }
+            },
+            help: "config [--explain|show|init [--force]|check]",
+        },
+        "marker": {
+            do: func (args *SimpleStack) {
+                path := writableConfigPath()
+                prefix := ""
+                suffix := ""
+                markerFlags := map[string]func(*SimpleStack){
+                    "--file": func (args *SimpleStack) {
+                        path = parseString(args)
+                    },
+                    "--prefix": func (args *SimpleStack) {
+                        prefix = parseString(args)
+                    },
+                    "--suffix": func (args *SimpleStack) {
+                        suffix = parseString(args)
+                    },
+                }
+                parseCmdFlags(args, markerFlags)
+                subCmd := parseString(args)
+                switch subCmd {
+                case "add":
+                    marker := parseString(args)
+                    parseCmdFlags(args, markerFlags)
+                    markerAdd(path, marker, prefix, suffix)
+                case "rm":
+                    marker := parseString(args)
+                    parseCmdFlags(args, markerFlags)
+                    markerRm(path, marker)
+                case "rename":
+                    oldMarker := parseString(args)
+                    newMarker := parseString(args)
+                    parseCmdFlags(args, markerFlags)
+                    dir := parseDirArg(args)
+                    markerRename(path, oldMarker, newMarker, dir, conf)
+                case "list":
+                    markerList(conf)
+                default:
+                    fmt.Println(cmds["marker"].help)
+                }
+            },
+            help: "marker [--file FILE] add <NAME> [--prefix P] [--suffix S] | rm <NAME> | rename <OLD> <NEW> [PATH] | list",
+        },
         "i": {
             do: func (args *SimpleStack) {
                 dir := parseDir(args)
@@ -207,9 +834,12 @@ func Parse(args []string) {
         "help": {
             do: func (args *SimpleStack) {
                 if args.Size() > 0 {
-                    cmd, found := cmds[args.Peek()]
-                    if found {
+                    if cmd, found := cmds[args.Peek()]; found {
                         fmt.Println(cmd.help)
+                    } else if flag, found := flags[args.Peek()]; found && flag.help != "" {
+                        fmt.Println(flag.help)
+                    } else if alias, found := aliases[args.Peek()]; found {
+                        fmt.Println(alias.help)
                     } else {
                         fmt.Println(fmt.Sprintf(`Unrecognized command help %s. See 'crumb help'`, args.Peek()))
                     }
@@ -227,9 +857,15 @@ func Parse(args []string) {
 
     argStack := NewSimpleStack(args)
     for argStack.Size() > 0 {
+        splitFlagValue(argStack, func (name string) bool {
+            _, found := flags[name]
+            return found
+        })
         arg := argStack.Pop()
         if flag, found := flags[arg]; found {
             flag.do(argStack)
+        } else if alias, found := aliases[arg]; found {
+            alias.do(argStack)
         } else {
             cmd, found := cmds[arg]
             if found {
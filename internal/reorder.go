@@ -0,0 +1,139 @@
+package crumb
+
+import (
+    "bufio"
+    "fmt"
+    "log"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Reorders the listed crumbs, given by line number in file order. Crumbs
+// hidden by filters keep their lines.
+type reorderFn func (lineNumbers []int, selected map[int]bool) []int
+
+func moveUp(lineNumbers []int, selected map[int]bool) []int {
+    for i := 1; i < len(lineNumbers); i++ {
+        if selected[lineNumbers[i]] && !selected[lineNumbers[i - 1]] {
+            lineNumbers[i], lineNumbers[i - 1] = lineNumbers[i - 1], lineNumbers[i]
+        }
+    }
+    return lineNumbers
+}
+
+func moveDown(lineNumbers []int, selected map[int]bool) []int {
+    for i := len(lineNumbers) - 2; i >= 0; i-- {
+        if selected[lineNumbers[i]] && !selected[lineNumbers[i + 1]] {
+            lineNumbers[i], lineNumbers[i + 1] = lineNumbers[i + 1], lineNumbers[i]
+        }
+    }
+    return lineNumbers
+}
+
+// The selected crumbs keep their order and start at position to, counted
+// from 1 among the listed crumbs in file order
+func moveTo(to int) reorderFn {
+    return func (lineNumbers []int, selected map[int]bool) []int {
+        var moved, rest []int
+        for _, lineNumber := range lineNumbers {
+            if selected[lineNumber] {
+                moved = append(moved, lineNumber)
+            } else {
+                rest = append(rest, lineNumber)
+            }
+        }
+        at := to - 1
+        if at < 0 {
+            at = 0
+        } else if at > len(rest) {
+            at = len(rest)
+        }
+        return append(append(append([]int{}, rest[:at]...), moved...), rest[at:]...)
+    }
+}
+
+// Places the lines of order into the lines the listed crumbs hold in the file
+func permuteCrumbLines(crumbLines []string, order []int, conf *Config) string {
+    slots := append([]int{}, order...)
+    sort.Ints(slots)
+
+    newLines := append([]string{}, crumbLines...)
+    for i, slot := range slots {
+        newLines[slot] = crumbLines[order[i]]
+    }
+    return newFileContent(newLines, nil, nil, conf)
+}
+
+// Crumbs are listed and selected in file order while reordering, the order
+// the moves apply to, whatever the configured sorts
+func reorderCrumbFile(crumbFilePath string, input string, reorder reorderFn, conf *Config) {
+    crumbLines := strings.Split(readFile(crumbFilePath), "\n")
+    _, lineNumbers := getCrumbsFromLines(crumbLines, activeFilter(conf), sortManual(), conf)
+
+    selected := make(map[int]bool)
+    for _, lineNumber := range parseSelection(input, lineNumbers) {
+        selected[lineNumber] = true
+    }
+
+    fileOrder := append([]int{}, lineNumbers...)
+    sort.Ints(fileOrder)
+    writeFile(crumbFilePath, permuteCrumbLines(crumbLines, reorder(fileOrder, selected), conf))
+}
+
+func reorderCrumbs(dir string, input string, reorder reorderFn, conf *Config) {
+    crumbFilePath := filepath.Join(dir, conf.CrumbFileName)
+    if !fileExists(crumbFilePath) {
+        log.Fatal(fmt.Sprintf("No crumb file at %s", crumbFilePath))
+    }
+    reorderCrumbFile(crumbFilePath, input, reorder, conf)
+    printManualOrder(dir, crumbFilePath, conf)
+}
+
+func printManualOrder(dir string, crumbFilePath string, conf *Config) {
+    crumbs, _ := getCrumbsFromLines(strings.Split(readFile(crumbFilePath), "\n"), activeFilter(conf), sortManual(), conf)
+    fmt.Println(preSufFixString(conf.Header, dir))
+    printCrumbs(crumbs, true, conf)
+}
+
+func reorderInteractive(dir string, cmdName string, reader *bufio.Reader, conf *Config) {
+    crumbFilePath := filepath.Join(dir, conf.CrumbFileName)
+    if !fileExists(crumbFilePath) {
+        return
+    }
+    printManualOrder(dir, crumbFilePath, conf)
+    fmt.Printf("%s>> ", cmdName)
+    input, _ := reader.ReadString('\n')
+    input = strings.TrimSuffix(input, "\n")
+
+    var reorder reorderFn
+    switch cmdName {
+    case "mv-up":
+        reorder = moveUp
+    case "mv-down":
+        reorder = moveDown
+    default:
+        fmt.Printf("to>> ")
+        to, _ := reader.ReadString('\n')
+        n, err := strconv.Atoi(strings.TrimSuffix(to, "\n"))
+        if err != nil {
+            fmt.Printf("Could not parse position %s\n", strings.TrimSuffix(to, "\n"))
+            return
+        }
+        reorder = moveTo(n)
+    }
+    reorderCrumbFile(crumbFilePath, input, reorder, conf)
+}
+
+// Writes the order of the active sorts back into the crumb file
+func sortWrite(dir string, conf *Config) {
+    crumbFilePath := filepath.Join(dir, conf.CrumbFileName)
+    if !fileExists(crumbFilePath) {
+        log.Fatal(fmt.Sprintf("No crumb file at %s", crumbFilePath))
+    }
+    crumbLines := strings.Split(readFile(crumbFilePath), "\n")
+    _, lineNumbers := getCrumbsFromLines(crumbLines, activeFilter(conf), buildSorts(conf.Sorts), conf)
+    writeFile(crumbFilePath, permuteCrumbLines(crumbLines, lineNumbers, conf))
+    fmt.Printf("Wrote the order of %d crumbs to %s\n", len(lineNumbers), crumbFilePath)
+}
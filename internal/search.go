@@ -0,0 +1,210 @@
+package crumb
+
+import (
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+// Text searches register how to find their matches so listings can
+// highlight them and sortFuzzy can rank by score, both are reset whenever
+// the active filter is rebuilt
+var textHighlights []func (string) [][]int
+var fuzzyScorers []func (string) int
+
+func resetTextSearches() {
+    textHighlights = nil
+    fuzzyScorers = nil
+}
+
+var caseModes = []string{"smartCase", "ignoreCase", "matchCase"}
+
+// Set by --ignoreCase, --matchCase and --smartCase for the search filters
+// given after it
+var searchCaseMode string
+
+func checkSearchArgs(name string, args []string) error {
+    if len(args) > 2 {
+        return fmt.Errorf("filter %s takes a pattern and an optional case mode, got %d args", name, len(args))
+    }
+    if len(args) == 2 && !containsString(caseModes, args[1]) {
+        return fmt.Errorf("filter %s got unknown case mode %q, expected one of %s", name, args[1], strings.Join(caseModes, ", "))
+    }
+    if name == "matches" {
+        if _, err := regexp.Compile(args[0]); err != nil {
+            return fmt.Errorf("filter %s got an invalid regexp: %s", name, err)
+        }
+    }
+    return nil
+}
+
+// smartCase ignores case unless the pattern has an upper case letter
+func ignoreCase(args []string) bool {
+    mode := "smartCase"
+    if len(args) > 1 {
+        mode = args[1]
+    }
+    switch mode {
+    case "ignoreCase":
+        return true
+    case "matchCase":
+        return false
+    }
+    return strings.ToLower(args[0]) == args[0]
+}
+
+func searchRegexp(pattern string, ignore bool) *regexp.Regexp {
+    if ignore {
+        pattern = "(?i)" + pattern
+    }
+    return regexp.MustCompile(pattern)
+}
+
+func contains(args []string) filter {
+    re := searchRegexp(regexp.QuoteMeta(args[0]), ignoreCase(args))
+    textHighlights = append(textHighlights, func (text string) [][]int {
+        return re.FindAllStringIndex(text, -1)
+    })
+    return func (crumb Crumb) bool {
+        return re.MatchString(crumb.text)
+    }
+}
+
+func matches(args []string) filter {
+    re := searchRegexp(args[0], ignoreCase(args))
+    textHighlights = append(textHighlights, func (text string) [][]int {
+        return re.FindAllStringIndex(text, -1)
+    })
+    return func (crumb Crumb) bool {
+        return re.MatchString(crumb.text)
+    }
+}
+
+// Scores pattern as a subsequence of text, consecutive runes and runes
+// starting a word score higher. The byte ranges of the matched runes are
+// returned along with the score, which is -1 when there is no match.
+func fuzzyMatch(pattern string, text string, ignore bool) (int, [][]int) {
+    equal := func (a rune, b rune) bool {
+        if ignore {
+            return unicode.ToLower(a) == unicode.ToLower(b)
+        }
+        return a == b
+    }
+    patternRunes := []rune(pattern)
+    if len(patternRunes) == 0 {
+        return 0, nil
+    }
+
+    bestScore, bestRanges := -1, [][]int(nil)
+    for start, r := range text {
+        if !equal(r, patternRunes[0]) {
+            continue
+        }
+        score, ranges, matched := 0, [][]int(nil), 0
+        prevEnd := -1
+        prevRune, _ := utf8.DecodeLastRuneInString(text[:start])
+        for i, r := range text[start:] {
+            if matched == len(patternRunes) {
+                break
+            }
+            i += start
+            if equal(r, patternRunes[matched]) {
+                score++
+                if i == prevEnd {
+                    score += 2
+                    ranges[len(ranges) - 1][1] = i + utf8.RuneLen(r)
+                } else {
+                    ranges = append(ranges, []int{i, i + utf8.RuneLen(r)})
+                }
+                if !unicode.IsLetter(prevRune) && !unicode.IsDigit(prevRune) {
+                    score += 3
+                }
+                prevEnd = i + utf8.RuneLen(r)
+                matched++
+            }
+            prevRune = r
+        }
+        if matched == len(patternRunes) && score > bestScore {
+            bestScore, bestRanges = score, ranges
+        }
+    }
+    return bestScore, bestRanges
+}
+
+func fuzzy(args []string) filter {
+    pattern, ignore := args[0], ignoreCase(args)
+    fuzzyScorers = append(fuzzyScorers, func (text string) int {
+        score, _ := fuzzyMatch(pattern, text, ignore)
+        return score
+    })
+    textHighlights = append(textHighlights, func (text string) [][]int {
+        _, ranges := fuzzyMatch(pattern, text, ignore)
+        return ranges
+    })
+    return func (crumb Crumb) bool {
+        score, _ := fuzzyMatch(pattern, crumb.text, ignore)
+        return score >= 0
+    }
+}
+
+func fuzzyScore(crumb Crumb) int {
+    total := 0
+    for _, scorer := range fuzzyScorers {
+        if score := scorer(crumb.text); score > 0 {
+            total += score
+        }
+    }
+    return total
+}
+
+// Merged and ordered byte ranges of every active search match in text
+func highlightRanges(text string) [][]int {
+    var ranges [][]int
+    for _, highlight := range textHighlights {
+        for _, r := range highlight(text) {
+            if r[0] < r[1] {
+                ranges = append(ranges, r)
+            }
+        }
+    }
+    sort.Slice(ranges, func (i, j int) bool {
+        return ranges[i][0] < ranges[j][0]
+    })
+
+    var merged [][]int
+    for _, r := range ranges {
+        if last := len(merged) - 1; last >= 0 && r[0] <= merged[last][1] {
+            if r[1] > merged[last][1] {
+                merged[last][1] = r[1]
+            }
+            continue
+        }
+        merged = append(merged, []int{r[0], r[1]})
+    }
+    return merged
+}
+
+// The style of the surrounding text is restored after each match as the
+// highlight resets it
+func highlightText(text string, base PreSufFix, conf *Config) string {
+    ranges := highlightRanges(text)
+    if len(ranges) == 0 {
+        return text
+    }
+
+    var builder strings.Builder
+    last := 0
+    for _, r := range ranges {
+        builder.WriteString(text[last:r[0]])
+        builder.WriteString(preSufFixString(conf.Highlight, text[r[0]:r[1]]))
+        if colorEnabled {
+            builder.WriteString(ansiStyle(base))
+        }
+        last = r[1]
+    }
+    builder.WriteString(text[last:])
+    return builder.String()
+}
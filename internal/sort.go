@@ -3,23 +3,36 @@ package crumb
 import (
     "log"
     "fmt"
+    "sort"
+    "strconv"
     "strings"
+    "time"
 )
 
-type less func (int, int) bool
+// A crumb along with its position in the crumb file
+type sortItem struct {
+    crumb Crumb
+    position int
+}
+
+// Negative when a goes before b, positive when after and 0 on a tie
+type compareFn func (sortItem, sortItem) int
+
 type lessFnI interface {
-    applyFn([]string) func (func (int) Crumb) less
+    applyFn([]string) compareFn
     buildDesc(*SimpleStack) FunctionDesc
+    checkArgs([]string) error
 }
 
 type lessFn struct {
     name string
-    fn func (func (int) Crumb) less
+    fn func () compareFn
 }
 
 type lessArgsFn struct {
     name string
-    fn func ([]string) func (func (int) Crumb) less
+    fn func ([]string) compareFn
+    check func (string, []string) error
 }
 
 var sortMap = map[string]lessFnI{
@@ -35,10 +48,27 @@ var sortMap = map[string]lessFnI{
         name: "sortMarked",
         fn: sortMarked,
     },
+    "sortWorkflow": lessFn{
+        name: "sortWorkflow",
+        fn: sortWorkflow,
+    },
     "sortMarkedOrder": lessArgsFn{
         name: "sortMarkedOrder",
         fn: sortMarkedOrder,
     },
+    "sortManual": lessFn{
+        name: "sortManual",
+        fn: sortManual,
+    },
+    "sortFuzzy": lessFn{
+        name: "sortFuzzy",
+        fn: sortFuzzy,
+    },
+    "sortBy": lessArgsFn{
+        name: "sortBy",
+        fn: sortBy,
+        check: checkSortKeys,
+    },
 }
 
 func (f lessFn) buildDesc(_ *SimpleStack) FunctionDesc {
@@ -57,54 +87,313 @@ func (f lessArgsFn) buildDesc(s *SimpleStack) FunctionDesc {
     };
 }
 
-func (f lessFn) applyFn(_ []string) func (func(int) Crumb) less {
-    return f.fn
+func (f lessFn) checkArgs(args []string) error {
+    if len(args) != 0 {
+        return fmt.Errorf("sort %s takes no args, got %d", f.name, len(args))
+    }
+    return nil
+}
+
+func (f lessArgsFn) checkArgs(args []string) error {
+    if len(args) == 0 {
+        return fmt.Errorf("sort %s needs atleast one arg", f.name)
+    }
+    if f.check != nil {
+        return f.check(f.name, args)
+    }
+    return nil
 }
 
-func (f lessArgsFn) applyFn(args []string) func (func(int) Crumb) less {
+func (f lessFn) applyFn(_ []string) compareFn {
+    return f.fn()
+}
+
+func (f lessArgsFn) applyFn(args []string) compareFn {
     return f.fn(args)
 }
 
-func sortNone(_ func (int) Crumb) less {
-    return func (i, j int) bool {
-        return i < j
+func compareInt(a int, b int) int {
+    if a < b {
+        return -1
+    }
+    if a > b {
+        return 1
+    }
+    return 0
+}
+
+func compareTime(a time.Time, b time.Time) int {
+    if a.Before(b) {
+        return -1
+    }
+    if a.After(b) {
+        return 1
     }
+    return 0
 }
 
-func sortReverse(_ func (int) Crumb) less {
-    return func (i, j int) bool {
-        return i > j
+// Leaves the order to the other sorts, crumbs they tie on keep the order
+// of the file
+func sortNone() compareFn {
+    return func (a, b sortItem) int {
+        return 0
     }
 }
 
-func sortMarked(crumbs func (int) Crumb) less {
-    return func (i, j int) bool {
-        return crumbs(i).marker == "" && crumbs(j).marker != ""
+// The order crumbs were arranged in by mv-up, mv-down, reorder and sort
+// --write, which is the order of the file. It takes precedence over every
+// sort given before it.
+func sortManual() compareFn {
+    return filePosition
+}
+
+func filePosition(a, b sortItem) int {
+    return compareInt(a.position, b.position)
+}
+
+func sortReverse() compareFn {
+    return func (a, b sortItem) int {
+        return compareInt(b.position, a.position)
     }
 }
 
-func sortMarkedOrder(args []string) func (func (int) Crumb) less {
-    return func (crumbs func (int) Crumb) less {
-        return func (i, j int) bool {
-            for _, marker := range args {
-                if crumbs(i).marker == marker {
-                    return true
-                }
-                if crumbs(j).marker == marker {
-                    return false
-                }
+func sortMarked() compareFn {
+    return func (a, b sortItem) int {
+        return compareInt(boolInt(a.crumb.marker != ""), boolInt(b.crumb.marker != ""))
+    }
+}
+
+func markerOrder(markers []string) func (Crumb) int {
+    return func (crumb Crumb) int {
+        for i, marker := range markers {
+            if crumb.marker == marker {
+                return i
+            }
+        }
+        return len(markers)
+    }
+}
+
+func sortMarkedOrder(args []string) compareFn {
+    order := markerOrder(args)
+    return func (a, b sortItem) int {
+        return compareInt(order(a.crumb), order(b.crumb))
+    }
+}
+
+// Crumbs follow the order of the workflow states, crumbs outside of the
+// workflow come last
+func sortWorkflow() compareFn {
+    return func (a, b sortItem) int {
+        return compareInt(workflowOrder(a.crumb), workflowOrder(b.crumb))
+    }
+}
+
+func workflowOrder(crumb Crumb) int {
+    if p := workflowPosition(crumb.marker, conf); p != -1 {
+        return p
+    }
+    return len(conf.Workflow.States)
+}
+
+// Best fuzzy matches first, by the score of every active fuzzy filter
+func sortFuzzy() compareFn {
+    return func (a, b sortItem) int {
+        return compareInt(fuzzyScore(b.crumb), fuzzyScore(a.crumb))
+    }
+}
+
+func compareFieldValues(a string, b string) int {
+    if spanA, err := parseTimeSpan(a); err == nil {
+        if spanB, err := parseTimeSpan(b); err == nil {
+            return compareTime(spanA.start, spanB.start)
+        }
+    }
+    if numA, err := strconv.ParseFloat(a, 64); err == nil {
+        if numB, err := strconv.ParseFloat(b, 64); err == nil {
+            if numA < numB {
+                return -1
+            } else if numA > numB {
+                return 1
             }
-            return false
+            return 0
         }
     }
+    return strings.Compare(a, b)
+}
+
+// A key of a sortBy spec, crumbs the key is not present for go last
+// whatever the direction
+type sortKey struct {
+    present func (Crumb) bool
+    compare compareFn
 }
 
-func buildSorts(sortFunctions []FunctionDesc) []func (func (int) Crumb) less {
-    var sortFns []func (func (int) Crumb) less
+func dateKey(date func (Crumb) *time.Time) sortKey {
+    return sortKey{
+        present: func (crumb Crumb) bool {
+            return date(crumb) != nil
+        },
+        compare: func (a, b sortItem) int {
+            return compareTime(*date(a.crumb), *date(b.crumb))
+        },
+    }
+}
+
+// "order=" and "field." keys take the rest of the key as their arg
+var sortKeys = map[string]func (string) sortKey{
+    "created": func (_ string) sortKey {
+        return dateKey(func (crumb Crumb) *time.Time {
+            return crumb.createdDate
+        })
+    },
+    "modified": func (_ string) sortKey {
+        return dateKey(modifiedDate)
+    },
+    "due": func (_ string) sortKey {
+        return dateKey(crumbDue)
+    },
+    "text": func (_ string) sortKey {
+        return sortKey{compare: func (a, b sortItem) int {
+            if cmp := strings.Compare(strings.ToLower(a.crumb.text), strings.ToLower(b.crumb.text)); cmp != 0 {
+                return cmp
+            }
+            return strings.Compare(a.crumb.text, b.crumb.text)
+        }}
+    },
+    "marker": func (_ string) sortKey {
+        return sortKey{compare: func (a, b sortItem) int {
+            return strings.Compare(a.crumb.marker, b.crumb.marker)
+        }}
+    },
+    "order=": func (markers string) sortKey {
+        return sortKey{compare: sortMarkedOrder(strings.Split(markers, "|"))}
+    },
+    "workflow": func (_ string) sortKey {
+        return sortKey{compare: sortWorkflow()}
+    },
+    "manual": func (_ string) sortKey {
+        return sortKey{compare: sortManual()}
+    },
+    "position": func (_ string) sortKey {
+        return sortKey{compare: filePosition}
+    },
+    "score": func (_ string) sortKey {
+        return sortKey{compare: sortFuzzy()}
+    },
+    "field.": func (name string) sortKey {
+        return sortKey{
+            present: func (crumb Crumb) bool {
+                _, found := crumbFields(crumb)[name]
+                return found
+            },
+            compare: func (a, b sortItem) int {
+                return compareFieldValues(crumbFields(a.crumb)[name], crumbFields(b.crumb)[name])
+            },
+        }
+    },
+}
+
+// Parses "KEY[:asc|:desc]" into a compareFn
+func parseSortKey(spec string) (compareFn, error) {
+    name, desc := spec, false
+    if i := strings.LastIndex(spec, ":"); i != -1 {
+        switch spec[i + 1:] {
+        case "asc":
+            name = spec[:i]
+        case "desc":
+            name, desc = spec[:i], true
+        default:
+            return nil, fmt.Errorf("unknown direction %q in %q, expected asc or desc", spec[i + 1:], spec)
+        }
+    }
+
+    var key sortKey
+    if keyFn, found := sortKeys[name]; found && !strings.HasSuffix(name, ".") && !strings.HasSuffix(name, "=") {
+        key = keyFn("")
+    }
+    for _, prefix := range []string{"order=", "field."} {
+        if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+            key = sortKeys[prefix](strings.TrimPrefix(name, prefix))
+        }
+    }
+    if key.compare == nil {
+        return nil, fmt.Errorf("unknown sort key %q, expected one of created, modified, due, text, marker, order=A|B, workflow, manual, position, score or field.NAME", name)
+    }
+
+    return func (a, b sortItem) int {
+        if key.present != nil {
+            presentA, presentB := key.present(a.crumb), key.present(b.crumb)
+            if !presentA || !presentB {
+                return compareInt(boolInt(!presentA), boolInt(!presentB))
+            }
+        }
+        if desc {
+            return key.compare(b, a)
+        }
+        return key.compare(a, b)
+    }, nil
+}
+
+func boolInt(b bool) int {
+    if b {
+        return 1
+    }
+    return 0
+}
+
+func checkSortKeys(name string, args []string) error {
+    for _, arg := range args {
+        if _, err := parseSortKey(arg); err != nil {
+            return fmt.Errorf("sort %s %s", name, err)
+        }
+    }
+    return nil
+}
+
+// Keys are compared in order, each later key only breaking ties
+func sortBy(args []string) compareFn {
+    var keys []compareFn
+    for _, arg := range args {
+        key, err := parseSortKey(arg)
+        if err != nil {
+            log.Fatal(err)
+        }
+        keys = append(keys, key)
+    }
+    return chainCompare(keys)
+}
+
+func chainCompare(keys []compareFn) compareFn {
+    return func (a, b sortItem) int {
+        for _, key := range keys {
+            if cmp := key(a, b); cmp != 0 {
+                return cmp
+            }
+        }
+        return 0
+    }
+}
+
+// The last sort has the final say and earlier ones break its ties, the
+// order of the file breaks any remaining ones
+func buildSorts(sortFunctions []FunctionDesc) compareFn {
+    keys := []compareFn{filePosition}
     for _, sortFn := range sortFunctions {
-        if sort, ok := sortMap[sortFn.Name]; ok {
-            sortFns = append(sortFns, sort.applyFn(sortFn.Args))
+        sort, found := sortMap[sortFn.Name]
+        if !found {
+            log.Fatal(fmt.Sprintf("Unknown sort %s", sortFn.Name))
         }
+        if err := sort.checkArgs(sortFn.Args); err != nil {
+            log.Fatal(err)
+        }
+        keys = append([]compareFn{sort.applyFn(sortFn.Args)}, keys...)
     }
-    return sortFns
+    return chainCompare(keys)
+}
+
+func sortItems(items []sortItem, order compareFn) {
+    sort.SliceStable(items, func (i, j int) bool {
+        return order(items[i], items[j]) < 0
+    })
 }
@@ -0,0 +1,157 @@
+package crumb
+
+import (
+    "encoding/json"
+    "fmt"
+    "log"
+    "os"
+    "time"
+)
+
+// A standup section, crumbs are placed by their marker. Without any markers
+// configured a role falls back on its fallback, if it has one.
+type standupRole struct {
+    key string
+    name string
+    setting string
+    markers func (*Config) []string
+    fallback func (string, *Config) bool
+}
+
+func (role standupRole) has(marker string, conf *Config) bool {
+    if markers := role.markers(conf); len(markers) > 0 {
+        return containsString(markers, marker)
+    }
+    return role.fallback != nil && role.fallback(marker, conf)
+}
+
+var standupRoles = []standupRole{
+    {"completed", "Completed", "Standup.Completed", func (conf *Config) []string {
+        return conf.Standup.Completed
+    }, isCompletedMarker},
+    {"in-progress", "In progress", "Standup.InProgress", func (conf *Config) []string {
+        return conf.Standup.InProgress
+    }, nil},
+    {"blocked", "Blocked", "Standup.Blocked", func (conf *Config) []string {
+        return conf.Standup.Blocked
+    }, nil},
+}
+
+// Used for plain and markdown unless `Formats` holds a format of that name
+var standupFormats = map[string]OutputFormat{
+    "plain": OutputFormat{
+        Group: "{{.Name}}:",
+        Crumb: "  - {{.Text}} ({{.ShortPath}})",
+    },
+    "markdown": OutputFormat{
+        Group: "**{{.Name}}**",
+        Crumb: "- {{.Text}} _({{.RepoPath}})_",
+    },
+}
+
+type standupCrumb struct {
+    ID string `json:"id"`
+    Path string `json:"path"`
+    Marker string `json:"marker"`
+    Text string `json:"text"`
+    Modified string `json:"modified"`
+}
+
+type standupSection struct {
+    Key string `json:"key"`
+    Name string `json:"name"`
+    Crumbs []standupCrumb `json:"crumbs"`
+}
+
+// Completed crumbs only count when modified since the start of the window,
+// in progress and blocked crumbs always do. filter is the Filters list, it
+// tends to hide completed crumbs so it only applies to the other roles. Where
+// applies to every role and is left to the listing.
+func standupSections(files []listedFile, since time.Time, filter filter, conf *Config) ([]crumbGroup, []standupSection) {
+    var groups []crumbGroup
+    var sections []standupSection
+    for _, role := range standupRoles {
+        group := crumbGroup{name: role.name}
+        section := standupSection{Key: role.key, Name: role.name, Crumbs: []standupCrumb{}}
+        for _, file := range files {
+            for _, entry := range file.crumbs {
+                if !role.has(entry.crumb.marker, conf) {
+                    continue
+                }
+                if role.key == "completed" && entry.data.Modified.Before(since) {
+                    continue
+                }
+                if role.key != "completed" && !filter(entry.crumb) {
+                    continue
+                }
+                group.crumbs = append(group.crumbs, entry)
+                section.Crumbs = append(section.Crumbs, standupCrumb{
+                    ID: entry.data.ID,
+                    Path: entry.data.Path,
+                    Marker: entry.data.Marker,
+                    Text: entry.data.Text,
+                    Modified: exportDate(modifiedDate(entry.crumb)),
+                })
+            }
+        }
+        groups = append(groups, group)
+        sections = append(sections, section)
+    }
+    return groups, sections
+}
+
+// The format is the first of --format, `CommandFormats.standup` and
+// `Standup.Format`, json is written as is and other names are looked up in
+// `Formats` then the plain and markdown formats
+func standupFormat(name string, conf *Config) *compiledFormat {
+    format, found := conf.Formats[name]
+    if !found {
+        format, found = standupFormats[name]
+    }
+    if !found {
+        format = OutputFormat{Crumb: name, Group: standupFormats["plain"].Group}
+    }
+    compiled, err := compileFormat(format)
+    if err != nil {
+        log.Fatal(fmt.Sprintf("Invalid format %s: %s", name, err))
+    }
+    return compiled
+}
+
+func standup(dir string, scope string, since string, formatName string, conf *Config) {
+    span, err := parseTimeSpan(since)
+    if err != nil {
+        log.Fatal(fmt.Sprintf("Invalid --since %s: %s", since, err))
+    }
+
+    for _, role := range standupRoles {
+        if len(role.markers(conf)) == 0 && role.fallback == nil {
+            fmt.Fprintf(os.Stderr, "crumb: %s lists nothing, set the markers it takes in %s\n", role.name, role.setting)
+        }
+    }
+
+    crumbFilePaths := crumbFilesInScope(scope, dir, conf)
+    filters, where := activeFilterParts(conf)
+    files := listCrumbFiles(crumbFilePaths, where, buildSorts(conf.Sorts), conf)
+    groups, sections := standupSections(files, span.start, filters, conf)
+
+    if formatName == "json" {
+        content, err := json.MarshalIndent(sections, "", "  ")
+        if err != nil {
+            log.Fatal(err)
+        }
+        fmt.Println(string(content))
+        return
+    }
+
+    format := standupFormat(formatName, conf)
+    for i, group := range groups {
+        if i > 0 {
+            fmt.Println()
+        }
+        fmt.Println(renderGroup(format, standupRoles[i].key, group, conf))
+        for _, entry := range group.crumbs {
+            fmt.Println(renderCrumb(format, entry.data))
+        }
+    }
+}
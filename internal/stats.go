@@ -0,0 +1,212 @@
+package crumb
+
+import (
+    "encoding/json"
+    "fmt"
+    "log"
+    "math"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "text/tabwriter"
+    "time"
+)
+
+type dirStats struct {
+    Dir string `json:"dir"`
+    Markers map[string]int `json:"markers"`
+    Total int `json:"total"`
+}
+
+type weekStats struct {
+    Week string `json:"week"`
+    Created int `json:"created"`
+    Completed int `json:"completed"`
+}
+
+type openCrumb struct {
+    ID string `json:"id"`
+    Path string `json:"path"`
+    Marker string `json:"marker"`
+    Text string `json:"text"`
+    Created string `json:"created"`
+    AgeDays float64 `json:"age_days"`
+}
+
+type crumbStats struct {
+    Since string `json:"since"`
+    Total int `json:"total"`
+    Open int `json:"open"`
+    AverageOpenAgeDays float64 `json:"average_open_age_days"`
+    Markers []string `json:"markers"`
+    Dirs []dirStats `json:"dirs"`
+    Weeks []weekStats `json:"weeks"`
+    Oldest []openCrumb `json:"oldest_open"`
+}
+
+func isOpenCrumb(crumb Crumb, conf *Config) bool {
+    if len(conf.Workflow.Open) > 0 || len(conf.Workflow.Closed) > 0 {
+        return isOpenMarker(crumb.marker, conf)
+    }
+    return crumb.marker == ""
+}
+
+// Weeks start on monday
+func startOfWeek(date time.Time) time.Time {
+    day := startOfDay(date)
+    return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+func statsMarker(marker string) string {
+    if marker == "" {
+        return emptyGroupNames["marker"]
+    }
+    return marker
+}
+
+func ageDays(age time.Duration) float64 {
+    return math.Round(age.Hours() / 24 * 10) / 10
+}
+
+// Markers and dirs count every crumb passing the filter, since only bounds
+// the weeks of created and completed crumbs
+func collectStats(files []fileCrumbs, since time.Time, oldest int, conf *Config) crumbStats {
+    now := crumbNow()
+    stats := crumbStats{Since: formatDate(since)}
+
+    var weeks []weekStats
+    weekIndex := make(map[string]int)
+    for week := startOfWeek(since); !week.After(now); week = week.AddDate(0, 0, 7) {
+        weekIndex[dayString(week)] = len(weeks)
+        weeks = append(weeks, weekStats{Week: dayString(week)})
+    }
+    countWeek := func (date *time.Time, count func (*weekStats)) {
+        if date == nil || date.Before(since) {
+            return
+        }
+        if i, found := weekIndex[dayString(startOfWeek(*date))]; found {
+            count(&weeks[i])
+        }
+    }
+
+    markers := make(map[string]bool)
+    var open []openCrumb
+    var openAge time.Duration
+    for _, file := range files {
+        if len(file.crumbs) == 0 {
+            continue
+        }
+        dir := dirStats{Dir: filepath.Dir(file.path), Markers: make(map[string]int)}
+        for i, crumb := range file.crumbs {
+            marker := statsMarker(crumb.marker)
+            markers[crumb.marker] = true
+            dir.Markers[marker]++
+            dir.Total++
+
+            countWeek(crumb.createdDate, func (week *weekStats) {
+                week.Created++
+            })
+            if isCompletedMarker(crumb.marker, conf) {
+                countWeek(crumb.modifiedDate, func (week *weekStats) {
+                    week.Completed++
+                })
+            }
+            if isOpenCrumb(crumb, conf) && crumb.createdDate != nil {
+                age := now.Sub(*crumb.createdDate)
+                openAge += age
+                open = append(open, openCrumb{
+                    ID: file.ids[i],
+                    Path: file.path,
+                    Marker: crumb.marker,
+                    Text: crumb.text,
+                    Created: formatDate(*crumb.createdDate),
+                    AgeDays: ageDays(age),
+                })
+            }
+        }
+        stats.Total += dir.Total
+        stats.Dirs = append(stats.Dirs, dir)
+    }
+
+    for marker := range markers {
+        stats.Markers = append(stats.Markers, marker)
+    }
+    sort.Slice(stats.Markers, func (i, j int) bool {
+        return groupLess("marker", stats.Markers[i], stats.Markers[j])
+    })
+    for i, marker := range stats.Markers {
+        stats.Markers[i] = statsMarker(marker)
+    }
+
+    stats.Open = len(open)
+    if len(open) > 0 {
+        stats.AverageOpenAgeDays = ageDays(openAge / time.Duration(len(open)))
+    }
+    sort.SliceStable(open, func (i, j int) bool {
+        return open[i].Created < open[j].Created
+    })
+    if len(open) > oldest {
+        open = open[:oldest]
+    }
+    stats.Oldest = open
+    stats.Weeks = weeks
+    return stats
+}
+
+func printStats(stats crumbStats) {
+    writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintf(writer, "dir\t%s\ttotal\n", strings.Join(stats.Markers, "\t"))
+    totals := make(map[string]int)
+    for _, dir := range stats.Dirs {
+        fmt.Fprintf(writer, "%s\t", shortPath(dir.Dir))
+        for _, marker := range stats.Markers {
+            fmt.Fprintf(writer, "%d\t", dir.Markers[marker])
+            totals[marker] += dir.Markers[marker]
+        }
+        fmt.Fprintf(writer, "%d\n", dir.Total)
+    }
+    fmt.Fprintf(writer, "total\t")
+    for _, marker := range stats.Markers {
+        fmt.Fprintf(writer, "%d\t", totals[marker])
+    }
+    fmt.Fprintf(writer, "%d\n", stats.Total)
+    writer.Flush()
+
+    fmt.Println()
+    writer = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintf(writer, "week\tcreated\tcompleted\n")
+    for _, week := range stats.Weeks {
+        fmt.Fprintf(writer, "%s\t%d\t%d\n", week.Week, week.Created, week.Completed)
+    }
+    writer.Flush()
+
+    fmt.Println()
+    fmt.Printf("%d open, %.1f days old on average\n", stats.Open, stats.AverageOpenAgeDays)
+    writer = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    for _, crumb := range stats.Oldest {
+        fmt.Fprintf(writer, "  %s\t%.0fd\t%s\t%s\n", crumb.Created[:10], crumb.AgeDays, shortPath(filepath.Dir(crumb.Path)), crumb.Text)
+    }
+    writer.Flush()
+}
+
+func stats(dir string, scope string, since string, oldest int, asJSON bool, conf *Config) {
+    span, err := parseTimeSpan(since)
+    if err != nil {
+        log.Fatal(fmt.Sprintf("Invalid --since %s: %s", since, err))
+    }
+
+    crumbFilePaths := crumbFilesInScope(scope, dir, conf)
+    files := readCrumbFiles(crumbFilePaths, activeFilter(conf), buildSorts(nil), conf)
+    crumbStats := collectStats(files, span.start, oldest, conf)
+
+    if !asJSON {
+        printStats(crumbStats)
+        return
+    }
+    content, err := json.MarshalIndent(crumbStats, "", "  ")
+    if err != nil {
+        log.Fatal(err)
+    }
+    fmt.Println(string(content))
+}
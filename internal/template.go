@@ -0,0 +1,212 @@
+package crumb
+
+import (
+    "bytes"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "text/template"
+    "time"
+)
+
+type compiledFormat struct {
+    crumb *template.Template
+    header *template.Template
+    footer *template.Template
+    group *template.Template
+}
+
+type crumbData struct {
+    Marker string
+    Text string
+    Styled string
+    Created time.Time
+    Modified time.Time
+    Age string
+    Index int
+    ID string
+    Path string
+    ShortPath string
+    RepoPath string
+    Fields map[string]string
+    Tags []string
+}
+
+type fileData struct {
+    Path string
+    File string
+    ShortPath string
+    RepoPath string
+    Styled string
+    Count int
+    Total int
+}
+
+// Set by --format, takes precedence over `CommandFormats` and `Format`
+var formatOverride string
+
+var templateFuncs = template.FuncMap{
+    "style": func (marker string, str string) string {
+        return preSufFixString(conf.Markers[marker], str)
+    },
+    "date": dayString,
+    "pad": func (width int, str string) string {
+        return fmt.Sprintf("%-*s", width, str)
+    },
+    "upper": strings.ToUpper,
+    "lower": strings.ToLower,
+}
+
+func dayString(date time.Time) string {
+    if date.IsZero() {
+        return ""
+    }
+    return date.Format("2006-01-02")
+}
+
+func compileTemplate(name string, text string) (*template.Template, error) {
+    if text == "" {
+        return nil, nil
+    }
+    return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+func compileFormat(format OutputFormat) (*compiledFormat, error) {
+    var compiled compiledFormat
+    var err error
+    if compiled.crumb, err = compileTemplate("Crumb", format.Crumb); err != nil {
+        return nil, err
+    }
+    if compiled.header, err = compileTemplate("Header", format.Header); err != nil {
+        return nil, err
+    }
+    if compiled.footer, err = compileTemplate("Footer", format.Footer); err != nil {
+        return nil, err
+    }
+    if compiled.group, err = compileTemplate("Group", format.Group); err != nil {
+        return nil, err
+    }
+    return &compiled, nil
+}
+
+// A format name not found in `Formats` is taken as an inline crumb template
+func outputFormat(cmd string, conf *Config) *compiledFormat {
+    name := formatOverride
+    if name == "" {
+        name = conf.CommandFormats[cmd]
+    }
+    if name == "" {
+        name = conf.Format
+    }
+
+    format, found := conf.Formats[name]
+    if !found {
+        format = OutputFormat{Crumb: name}
+    }
+    compiled, err := compileFormat(format)
+    if err != nil {
+        log.Fatal(fmt.Sprintf("Invalid format %s: %s", name, err))
+    }
+    return compiled
+}
+
+func executeTemplate(tmpl *template.Template, data interface{}) string {
+    var buf bytes.Buffer
+    if err := tmpl.Execute(&buf, data); err != nil {
+        log.Fatal(err)
+    }
+    return buf.String()
+}
+
+func shortPath(path string) string {
+    home := os.Getenv("HOME")
+    if home != "" && (path == home || strings.HasPrefix(path, home + string(filepath.Separator))) {
+        return "~" + path[len(home):]
+    }
+    return path
+}
+
+// Paths inside a repository are given relative to the repository's parent
+// so the repository name leads
+func repoPath(path string) string {
+    root := findRepoRoot(path)
+    if root == "" {
+        return shortPath(path)
+    }
+    if rel, err := filepath.Rel(filepath.Dir(root), path); err == nil {
+        return rel
+    }
+    return path
+}
+
+func relativeAge(date time.Time) string {
+    age := crumbNow().Sub(date)
+    switch {
+    case age < time.Minute:
+        return "now"
+    case age < time.Hour:
+        return fmt.Sprintf("%dm", int(age.Minutes()))
+    case age < 24 * time.Hour:
+        return fmt.Sprintf("%dh", int(age.Hours()))
+    case age < 14 * 24 * time.Hour:
+        return fmt.Sprintf("%dd", int(age.Hours() / 24))
+    case age < 365 * 24 * time.Hour:
+        return fmt.Sprintf("%dw", int(age.Hours() / 24 / 7))
+    }
+    return fmt.Sprintf("%dy", int(age.Hours() / 24 / 365))
+}
+
+func newFileData(crumbFilePath string, count int, total int, conf *Config) fileData {
+    dir := filepath.Join(crumbFilePath, "..")
+    return fileData{
+        Path: dir,
+        File: crumbFilePath,
+        ShortPath: shortPath(dir),
+        RepoPath: repoPath(dir),
+        Styled: preSufFixString(conf.Header, dir),
+        Count: count,
+        Total: total,
+    }
+}
+
+func newCrumbData(crumb Crumb, index int, id string, file fileData, conf *Config) crumbData {
+    data := crumbData{
+        Marker: crumb.marker,
+        Text: crumb.text,
+        Styled: formatCrumb(crumb, conf),
+        Index: index,
+        ID: id,
+        Path: file.Path,
+        ShortPath: file.ShortPath,
+        RepoPath: file.RepoPath,
+        Fields: crumbFields(crumb),
+        Tags: crumbTags(crumb),
+    }
+    if crumb.createdDate != nil {
+        data.Created = *crumb.createdDate
+        data.Modified = *crumb.createdDate
+    }
+    if crumb.modifiedDate != nil {
+        data.Modified = *crumb.modifiedDate
+    }
+    if !data.Modified.IsZero() {
+        data.Age = relativeAge(data.Modified)
+    }
+    return data
+}
+
+func renderHeader(format *compiledFormat, file fileData) string {
+    if format.header == nil {
+        return file.Styled
+    }
+    return executeTemplate(format.header, file)
+}
+
+func renderCrumb(format *compiledFormat, crumb crumbData) string {
+    if format.crumb == nil {
+        return crumb.Styled
+    }
+    return executeTemplate(format.crumb, crumb)
+}
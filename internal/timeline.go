@@ -0,0 +1,92 @@
+package crumb
+
+import (
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "text/tabwriter"
+    "time"
+)
+
+// A crumb being created or modified, crumb files keep no history so a crumb
+// modified several times only shows its last modification
+type timelineEvent struct {
+    date time.Time
+    kind string
+    path string
+    crumb Crumb
+}
+
+// The author:NAME field of a crumb, crumbs carry no other authorship
+func crumbAuthor(crumb Crumb) string {
+    return crumbFields(crumb)["author"]
+}
+
+func timelineEvents(files []fileCrumbs, since time.Time, author string) []timelineEvent {
+    var events []timelineEvent
+    for _, file := range files {
+        for _, crumb := range file.crumbs {
+            if author != "" && !strings.EqualFold(crumbAuthor(crumb), author) {
+                continue
+            }
+            if crumb.createdDate != nil && !crumb.createdDate.Before(since) {
+                events = append(events, timelineEvent{*crumb.createdDate, "created", file.path, crumb})
+            }
+            if crumb.modifiedDate != nil && !crumb.modifiedDate.Before(since) {
+                events = append(events, timelineEvent{*crumb.modifiedDate, "modified", file.path, crumb})
+            }
+        }
+    }
+    sort.SliceStable(events, func (i, j int) bool {
+        return events[i].date.Before(events[j].date)
+    })
+    return events
+}
+
+func eventText(event timelineEvent) string {
+    if event.crumb.marker == "" {
+        return event.crumb.text
+    }
+    return fmt.Sprintf("[%s] %s", event.crumb.marker, event.crumb.text)
+}
+
+func printTimeline(events []timelineEvent, oneline bool, conf *Config) {
+    writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    lastDay := ""
+    for _, event := range events {
+        dir := shortPath(filepath.Dir(event.path))
+        if oneline {
+            fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", event.date.Format("2006-01-02 15:04"), event.kind, dir, eventText(event))
+            continue
+        }
+        if day := dayString(event.date); day != lastDay {
+            writer.Flush()
+            if lastDay != "" {
+                fmt.Println()
+            }
+            fmt.Println(preSufFixString(conf.Group, event.date.Format("2006-01-02 Monday")))
+            lastDay = day
+        }
+        fmt.Fprintf(writer, "  %s\t%s\t%s\t%s\n", event.date.Format("15:04"), event.kind, dir, eventText(event))
+    }
+    writer.Flush()
+}
+
+func timeline(dir string, scope string, since string, author string, oneline bool, conf *Config) {
+    span, err := parseTimeSpan(since)
+    if err != nil {
+        log.Fatal(fmt.Sprintf("Invalid --since %s: %s", since, err))
+    }
+
+    crumbFilePaths := crumbFilesInScope(scope, dir, conf)
+    files := readCrumbFiles(crumbFilePaths, activeFilter(conf), buildSorts(nil), conf)
+    events := timelineEvents(files, span.start, author)
+    if len(events) == 0 {
+        fmt.Printf("Nothing happened since %s\n", formatDate(span.start))
+        return
+    }
+    printTimeline(events, oneline, conf)
+}
@@ -7,6 +7,7 @@ import (
     "io/ioutil"
     "errors"
     "path/filepath"
+    "strings"
 )
 
 func getWD() string {
@@ -33,6 +34,45 @@ func fileExists(filename string) bool {
     return !info.IsDir()
 }
 
+func pathExists(path string) bool {
+    _, err := os.Stat(path)
+    return err == nil
+}
+
+// The absolute path with symlinks resolved, the cleaned absolute path when
+// it can not be resolved
+func canonicalPath(path string) string {
+    abs, err := filepath.Abs(path)
+    if err != nil {
+        return path
+    }
+    if real, err := filepath.EvalSymlinks(abs); err == nil {
+        return real
+    }
+    return abs
+}
+
+// Expands environment variables and a leading "~"
+func expandPath(path string) string {
+    path = os.ExpandEnv(path)
+    if path == "~" || strings.HasPrefix(path, "~/") {
+        path = getHomePath() + path[1:]
+    }
+    return path
+}
+
+// The nearest dir from dir and up holding a .git, "" when there is none
+func findRepoRoot(dir string) string {
+    for basePath := dir; ; basePath = filepath.Dir(basePath) {
+        if _, err := os.Stat(filepath.Join(basePath, ".git")); err == nil {
+            return basePath
+        }
+        if filepath.Dir(basePath) == basePath {
+            return ""
+        }
+    }
+}
+
 func readFile(path string) string {
     content, err := ioutil.ReadFile(path)
     if err != nil {
@@ -80,3 +120,18 @@ func writeFile(path string, content string) {
     }
 }
 
+
+func appendFile(path string, content string) {
+    file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+    if err != nil {
+        log.Fatal(fmt.Sprintf("Unable to access %s for writing", path))
+    }
+
+    if _, err := file.Write([]byte(content)); err != nil {
+        log.Fatal(err)
+    }
+
+    if err := file.Close(); err != nil {
+        log.Fatal(err)
+    }
+}
@@ -0,0 +1,401 @@
+package crumb
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/pelletier/go-toml"
+)
+
+type configError struct {
+    path string
+    line int
+    key string
+    msg string
+}
+
+func (e configError) Error() string {
+    if e.line > 0 {
+        return fmt.Sprintf("%s:%d: %s: %s", e.path, e.line, e.key, e.msg)
+    }
+    return fmt.Sprintf("%s: %s: %s", e.path, e.key, e.msg)
+}
+
+var dateLikeMarkerRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$|^\d{2}:\d{2}:\d{2}$`)
+
+func editDistance(a string, b string) int {
+    prev := make([]int, len(b) + 1)
+    for j := range prev {
+        prev[j] = j
+    }
+    for i := 1; i <= len(a); i++ {
+        curr := make([]int, len(b) + 1)
+        curr[0] = i
+        for j := 1; j <= len(b); j++ {
+            cost := 1
+            if a[i - 1] == b[j - 1] {
+                cost = 0
+            }
+            curr[j] = curr[j - 1] + 1
+            if prev[j] + 1 < curr[j] {
+                curr[j] = prev[j] + 1
+            }
+            if prev[j - 1] + cost < curr[j] {
+                curr[j] = prev[j - 1] + cost
+            }
+        }
+        prev = curr
+    }
+    return prev[len(b)]
+}
+
+func didYouMean(name string, names []string) string {
+    best, bestDistance := "", 4
+    sort.Strings(names)
+    for _, candidate := range names {
+        if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance < bestDistance {
+            best, bestDistance = candidate, distance
+        }
+    }
+    if best == "" {
+        return ""
+    }
+    return fmt.Sprintf(", did you mean %s?", best)
+}
+
+func listTrees(tree *toml.Tree, key string) []*toml.Tree {
+    trees, _ := tree.Get(key).([]*toml.Tree)
+    return trees
+}
+
+func validateFunctionDescs(descs []FunctionDesc, tree *toml.Tree, path string, key string, kind string) []error {
+    var errs []error
+    trees := listTrees(tree, key)
+    for i, desc := range descs {
+        descKey := fmt.Sprintf("%s[%d]", key, i + 1)
+        line := 0
+        if i < len(trees) {
+            line = trees[i].Position().Line
+        }
+
+        var names []string
+        var checkArgs func([]string) error
+        if kind == "filter" {
+            for name, _ := range filterMap {
+                names = append(names, name)
+            }
+            if filter, found := filterMap[desc.Name]; found {
+                checkArgs = filter.checkArgs
+            }
+        } else {
+            for name, _ := range sortMap {
+                names = append(names, name)
+            }
+            if sort, found := sortMap[desc.Name]; found {
+                checkArgs = sort.checkArgs
+            }
+        }
+
+        if checkArgs == nil {
+            errs = append(errs, configError{path, line, descKey + ".Name",
+                fmt.Sprintf("unknown %s %q%s", kind, desc.Name, didYouMean(desc.Name, names))})
+        } else if err := checkArgs(desc.Args); err != nil {
+            errs = append(errs, configError{path, line, descKey + ".Args", err.Error()})
+        }
+    }
+    return errs
+}
+
+func validateMarkers(markers map[string]PreSufFix, tree *toml.Tree, path string, key string) []error {
+    var errs []error
+    var names []string
+    for marker, _ := range markers {
+        names = append(names, marker)
+    }
+    sort.Strings(names)
+
+    for _, marker := range names {
+        markerKey := key + "." + marker
+        line := tree.GetPosition(markerKey).Line
+        if marker == "" {
+            errs = append(errs, configError{path, line, markerKey, "marker name can not be empty"})
+        } else if strings.ContainsAny(marker, " \t") {
+            errs = append(errs, configError{path, line, markerKey, "marker name can not contain whitespace"})
+        } else if dateLikeMarkerRe.MatchString(marker) {
+            errs = append(errs, configError{path, line, markerKey, "marker name collides with the crumb date syntax"})
+        }
+    }
+    return errs
+}
+
+func validateStyle(style PreSufFix, tree *toml.Tree, path string, key string) []error {
+    var errs []error
+    if _, err := colorCode(style.Fg, false); err != nil && style.Fg != "" {
+        errs = append(errs, configError{path, tree.GetPosition(key + ".Fg").Line, key + ".Fg", err.Error()})
+    }
+    if _, err := colorCode(style.Bg, true); err != nil && style.Bg != "" {
+        errs = append(errs, configError{path, tree.GetPosition(key + ".Bg").Line, key + ".Bg", err.Error()})
+    }
+    return errs
+}
+
+func validateFormats(fileConf *Config, tree *toml.Tree, path string, prefix string) []error {
+    var errs []error
+    for name, format := range fileConf.Formats {
+        if _, err := compileFormat(format); err != nil {
+            key := prefix + "Formats." + name
+            errs = append(errs, configError{path, tree.GetPosition(key).Line, key, err.Error()})
+        }
+    }
+    inlineFormats := map[string]string{prefix + "Format": fileConf.Format}
+    for cmd, name := range fileConf.CommandFormats {
+        inlineFormats[prefix + "CommandFormats." + cmd] = name
+    }
+    for key, name := range inlineFormats {
+        if _, found := fileConf.Formats[name]; !found {
+            if _, err := compileTemplate(key, name); err != nil {
+                errs = append(errs, configError{path, tree.GetPosition(key).Line, key, err.Error()})
+            }
+        }
+    }
+    return errs
+}
+
+func validateWhere(where string, tree *toml.Tree, path string, key string) []error {
+    if where == "" {
+        return nil
+    }
+    if _, err := compileWhere(where); err != nil {
+        return []error{configError{path, tree.GetPosition(key).Line, key, err.Error()}}
+    }
+    return nil
+}
+
+func validateGroupBy(groupBy string, tree *toml.Tree, path string, key string) []error {
+    if err := checkGroupBy(groupBy); err != nil {
+        return []error{configError{path, tree.GetPosition(key).Line, key, err.Error()}}
+    }
+    return nil
+}
+
+func validateConfigTree(fileConf *Config, tree *toml.Tree, path string, prefix string) []error {
+    var errs []error
+    errs = append(errs, validateFunctionDescs(fileConf.Filters, tree, path, prefix + "Filters", "filter")...)
+    errs = append(errs, validateWhere(fileConf.Where, tree, path, prefix + "Where")...)
+    errs = append(errs, validateFunctionDescs(fileConf.Sorts, tree, path, prefix + "Sorts", "sort")...)
+    errs = append(errs, validateGroupBy(fileConf.GroupBy, tree, path, prefix + "GroupBy")...)
+    errs = append(errs, validateMarkers(fileConf.Markers, tree, path, prefix + "Markers")...)
+    for marker, style := range fileConf.Markers {
+        errs = append(errs, validateStyle(style, tree, path, prefix + "Markers." + marker)...)
+    }
+    errs = append(errs, validateStyle(fileConf.UnMarked, tree, path, prefix + "UnMarked")...)
+    errs = append(errs, validateStyle(fileConf.Header, tree, path, prefix + "Header")...)
+    errs = append(errs, validateStyle(fileConf.Selector, tree, path, prefix + "Selector")...)
+    errs = append(errs, validateStyle(fileConf.Highlight, tree, path, prefix + "Highlight")...)
+    errs = append(errs, validateStyle(fileConf.Group, tree, path, prefix + "Group")...)
+    errs = append(errs, validateFormats(fileConf, tree, path, prefix)...)
+    for name, view := range fileConf.Views {
+        viewKey := prefix + "Views." + name
+        errs = append(errs, validateFunctionDescs(view.Filters, tree, path, viewKey + ".Filters", "filter")...)
+        errs = append(errs, validateWhere(view.Where, tree, path, viewKey + ".Where")...)
+        errs = append(errs, validateFunctionDescs(view.Sorts, tree, path, viewKey + ".Sorts", "sort")...)
+        errs = append(errs, validateGroupBy(view.GroupBy, tree, path, viewKey + ".GroupBy")...)
+        if scope := view.Scope; scope != "" && scope != "ls" && scope != "ba" && scope != "wa" {
+            errs = append(errs, configError{path, tree.GetPosition(viewKey + ".Scope").Line, viewKey + ".Scope",
+                fmt.Sprintf("unknown scope %q, expected one of ls, ba or wa", scope)})
+        }
+        if _, found := fileConf.Formats[view.Format]; !found {
+            if _, err := compileTemplate(viewKey + ".Format", view.Format); err != nil {
+                errs = append(errs, configError{path, tree.GetPosition(viewKey + ".Format").Line, viewKey + ".Format", err.Error()})
+            }
+        }
+    }
+    if mode := fileConf.Walk.Symlinks; mode != "" && mode != "skip" && mode != "follow" {
+        errs = append(errs, configError{path, tree.GetPosition(prefix + "Walk.Symlinks").Line, prefix + "Walk.Symlinks",
+            fmt.Sprintf("unknown symlink mode %q, expected skip or follow", mode)})
+    }
+    for i, entry := range fileConf.StopAt {
+        if entry == "" {
+            errs = append(errs, configError{path, tree.GetPosition(prefix + "StopAt").Line, fmt.Sprintf("%sStopAt[%d]", prefix, i + 1),
+                "empty boundary, expected a dir or a name such as \".git\""})
+        }
+    }
+    if order := fileConf.BaOrder; order != "" && order != "nearest" && order != "top-down" {
+        errs = append(errs, configError{path, tree.GetPosition(prefix + "BaOrder").Line, prefix + "BaOrder",
+            fmt.Sprintf("unknown ba order %q, expected nearest or top-down", order)})
+    }
+    if target := fileConf.Add.Target; target != "" && target != "here" && target != "nearest" && target != "root" {
+        errs = append(errs, configError{path, tree.GetPosition(prefix + "Add.Target").Line, prefix + "Add.Target",
+            fmt.Sprintf("unknown add target %q, expected one of here, nearest or root", target)})
+    }
+    if newFile := fileConf.Add.NewFile; newFile != "" && newFile != "confirm" && newFile != "warn" && newFile != "create" {
+        errs = append(errs, configError{path, tree.GetPosition(prefix + "Add.NewFile").Line, prefix + "Add.NewFile",
+            fmt.Sprintf("unknown new file mode %q, expected one of confirm, warn or create", newFile)})
+    }
+    if window := fileConf.Standup.Window; window != "" {
+        if _, err := parseTimeSpan(window); err != nil {
+            errs = append(errs, configError{path, tree.GetPosition(prefix + "Standup.Window").Line, prefix + "Standup.Window", err.Error()})
+        }
+    }
+    if scope := fileConf.Standup.Scope; scope != "" && scope != "ls" && scope != "ba" && scope != "wa" {
+        errs = append(errs, configError{path, tree.GetPosition(prefix + "Standup.Scope").Line, prefix + "Standup.Scope",
+            fmt.Sprintf("unknown scope %q, expected one of ls, ba or wa", scope)})
+    }
+    if fileConf.Walk.Workers < 0 {
+        errs = append(errs, configError{path, tree.GetPosition(prefix + "Walk.Workers").Line, prefix + "Walk.Workers",
+            fmt.Sprintf("workers must not be negative, got %d", fileConf.Walk.Workers)})
+    }
+    for i, glob := range fileConf.Walk.Ignore {
+        if _, err := filepath.Match(glob, ""); err != nil {
+            errs = append(errs, configError{path, tree.GetPosition(prefix + "Walk.Ignore").Line, fmt.Sprintf("%sWalk.Ignore[%d]", prefix, i + 1),
+                fmt.Sprintf("invalid glob %q", glob)})
+        }
+    }
+    if mode := fileConf.Color; mode != "" && mode != "auto" && mode != "always" && mode != "never" {
+        errs = append(errs, configError{path, tree.GetPosition(prefix + "Color").Line, prefix + "Color",
+            fmt.Sprintf("unknown color mode %q, expected one of auto, always or never", mode)})
+    }
+    for i, alias := range fileConf.Alias {
+        if alias.Name == "" {
+            line := 0
+            if trees := listTrees(tree, prefix + "Alias"); i < len(trees) {
+                line = trees[i].Position().Line
+            }
+            errs = append(errs, configError{path, line, fmt.Sprintf("%sAlias[%d].Name", prefix, i + 1), "alias needs a name"})
+        }
+    }
+    return errs
+}
+
+func aliasPosition(name string) (string, int) {
+    path, line := configSources["Alias"], 0
+    for _, loaded := range loadedConfigs {
+        trees := listTrees(loaded.tree, "Alias")
+        for i, alias := range loaded.conf.Alias {
+            if alias.Name == name && i < len(trees) {
+                path, line = loaded.path, trees[i].Position().Line
+            }
+        }
+    }
+    return path, line
+}
+
+func validateAliases(conf *Config) []error {
+    aliases := make(map[string][]string)
+    for _, alias := range conf.Alias {
+        aliases[alias.Name] = alias.Args
+    }
+
+    var errs []error
+    reported := make(map[string]bool)
+    var visit func(string, []string)
+    visit = func (name string, trail []string) {
+        for i, visited := range trail {
+            if visited == name {
+                cycle := append(append([]string{}, trail[i:]...), name)
+                if !reported[cycle[0]] {
+                    for _, alias := range cycle {
+                        reported[alias] = true
+                    }
+                    path, line := aliasPosition(cycle[0])
+                    errs = append(errs, configError{path, line, "Alias",
+                        fmt.Sprintf("alias cycle %s", strings.Join(cycle, " -> "))})
+                }
+                return
+            }
+        }
+        for _, arg := range aliases[name] {
+            if _, found := aliases[arg]; found {
+                visit(arg, append(trail, name))
+            }
+        }
+    }
+
+    var names []string
+    for name, _ := range aliases {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        visit(name, nil)
+    }
+    return errs
+}
+
+func sourcePosition(key string) (string, int) {
+    path := explainSource(key)
+    for _, loaded := range loadedConfigs {
+        if loaded.path == path && loaded.tree.Has(key) {
+            return path, loaded.tree.GetPosition(key).Line
+        }
+    }
+    return path, 0
+}
+
+func validateWorkflow(conf *Config) []error {
+    var errs []error
+    checkMarkers := func (key string, markers []string) {
+        for _, marker := range markers {
+            if _, found := conf.Markers[marker]; !found && marker != "" {
+                path, line := sourcePosition(key)
+                errs = append(errs, configError{path, line, key, fmt.Sprintf("unknown marker %q", marker)})
+            }
+        }
+    }
+
+    checkMarkers("Workflow.States", conf.Workflow.States)
+    checkMarkers("Workflow.Open", conf.Workflow.Open)
+    checkMarkers("Workflow.Closed", conf.Workflow.Closed)
+
+    var froms []string
+    for from, _ := range conf.Workflow.Transitions {
+        froms = append(froms, from)
+    }
+    sort.Strings(froms)
+    for _, from := range froms {
+        key := "Workflow.Transitions." + from
+        checkMarkers(key, append([]string{from}, conf.Workflow.Transitions[from]...))
+    }
+
+    var checkboxes []string
+    for checkbox, _ := range conf.Markdown.Import {
+        checkboxes = append(checkboxes, checkbox)
+    }
+    sort.Strings(checkboxes)
+    for _, checkbox := range checkboxes {
+        checkMarkers("Markdown.Import." + checkbox, []string{conf.Markdown.Import[checkbox]})
+    }
+    return errs
+}
+
+func validateConfig(conf *Config) []error {
+    var errs []error
+    for _, loaded := range loadedConfigs {
+        errs = append(errs, validateConfigTree(loaded.conf, loaded.tree, loaded.path, "")...)
+
+        var profiles []string
+        for profile, _ := range loaded.conf.Profiles {
+            profiles = append(profiles, profile)
+        }
+        sort.Strings(profiles)
+        for _, profile := range profiles {
+            profileConf := loaded.conf.Profiles[profile]
+            errs = append(errs, validateConfigTree(&profileConf, loaded.tree, loaded.path, "Profiles." + profile + ".")...)
+        }
+    }
+    errs = append(errs, validateAliases(conf)...)
+    errs = append(errs, validateWorkflow(conf)...)
+    return errs
+}
+
+func checkConfig(conf *Config) bool {
+    errs := validateConfig(conf)
+    for _, err := range errs {
+        fmt.Fprintln(os.Stderr, err)
+    }
+    return len(errs) == 0
+}
@@ -0,0 +1,111 @@
+package crumb
+
+import (
+    "fmt"
+    "log"
+    "sort"
+)
+
+// What a view replaces, kept so interactive mode can switch between views
+type viewBase struct {
+    filters []FunctionDesc
+    where string
+    sorts []FunctionDesc
+    groupBy string
+    format string
+}
+
+func currentViewBase(conf *Config) viewBase {
+    return viewBase{
+        filters: conf.Filters,
+        where: conf.Where,
+        sorts: conf.Sorts,
+        groupBy: conf.GroupBy,
+        format: formatOverride,
+    }
+}
+
+func restoreViewBase(base viewBase, conf *Config) {
+    conf.Filters = base.filters
+    conf.Where = base.where
+    conf.Sorts = base.sorts
+    conf.GroupBy = base.groupBy
+    formatOverride = base.format
+}
+
+func findView(name string, conf *Config) ViewConf {
+    view, found := conf.Views[name]
+    if !found {
+        var names []string
+        for viewName, _ := range conf.Views {
+            names = append(names, viewName)
+        }
+        log.Fatal(fmt.Sprintf("No view named %s%s", name, didYouMean(name, names)))
+    }
+    return view
+}
+
+func applyView(view ViewConf, base viewBase, conf *Config) {
+    restoreViewBase(base, conf)
+    if view.InheritFilters {
+        conf.Filters = append(append([]FunctionDesc{}, base.filters...), view.Filters...)
+        if view.Where != "" {
+            conf.Where = andWhere(base.where, view.Where)
+        }
+    } else {
+        conf.Filters = view.Filters
+        conf.Where = view.Where
+    }
+    if len(view.Sorts) > 0 {
+        conf.Sorts = view.Sorts
+    }
+    if view.GroupBy != "" {
+        conf.GroupBy = view.GroupBy
+    }
+    if view.Format != "" && base.format == "" {
+        formatOverride = view.Format
+    }
+}
+
+func viewScope(view ViewConf) string {
+    if view.Scope == "" {
+        return "ls"
+    }
+    return view.Scope
+}
+
+func viewDepth(view ViewConf) int {
+    if view.Depth == 0 {
+        return walkDepth(conf)
+    }
+    return view.Depth
+}
+
+func runView(view ViewConf, dir string, conf *Config) {
+    switch viewScope(view) {
+    case "ls":
+        ls(dir, conf)
+    case "ba":
+        ba(dir, conf)
+    case "wa":
+        wa(dir, viewDepth(view), conf)
+    default:
+        log.Fatal(fmt.Sprintf("Unknown view scope %s, expected one of ls, ba or wa", view.Scope))
+    }
+}
+
+func viewNames(conf *Config) []string {
+    var names []string
+    for name, _ := range conf.Views {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+func printViews(conf *Config) {
+    for _, name := range viewNames(conf) {
+        view := conf.Views[name]
+        fmt.Printf("%s\t%s\t%s\n", name, viewScope(view), view.Description)
+    }
+}
@@ -2,11 +2,118 @@ package crumb
 
 import (
     "strconv"
-    "sort"
-    "path/filepath"
+    "strings"
+    "regexp"
+    "os"
     "fmt"
 )
 
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// Decided once by setupColor from --color, $NO_COLOR, `Color` and whether
+// stdout is a terminal
+var colorEnabled = true
+
+var colorNames = map[string]int{
+    "black": 0,
+    "red": 1,
+    "green": 2,
+    "yellow": 3,
+    "blue": 4,
+    "magenta": 5,
+    "cyan": 6,
+    "white": 7,
+}
+
+func isTerminal(file *os.File) bool {
+    info, err := file.Stat()
+    if err != nil {
+        return false
+    }
+    return info.Mode() & os.ModeCharDevice != 0
+}
+
+// A terminal to ask the user on, /dev/null is a character device too
+func isInteractive(file *os.File) bool {
+    if !isTerminal(file) {
+        return false
+    }
+    info, err := file.Stat()
+    devNull, devErr := os.Stat(os.DevNull)
+    return err == nil && (devErr != nil || !os.SameFile(info, devNull))
+}
+
+func setupColor(colorFlag string, conf *Config) error {
+    mode := colorFlag
+    if mode == "" && os.Getenv("NO_COLOR") != "" {
+        mode = "never"
+    }
+    if mode == "" {
+        mode = conf.Color
+    }
+
+    switch mode {
+    case "", "auto":
+        colorEnabled = isTerminal(os.Stdout)
+    case "always":
+        colorEnabled = true
+    case "never":
+        colorEnabled = false
+    default:
+        return fmt.Errorf("Unknown color mode %s, expected one of auto, always or never", mode)
+    }
+    return nil
+}
+
+// Colors are either named, "bright-" named, 0-255 or #rrggbb
+func colorCode(color string, background bool) (string, error) {
+    base, bright, extended := 30, 90, 38
+    if background {
+        base, bright, extended = 40, 100, 48
+    }
+
+    if code, found := colorNames[color]; found {
+        return strconv.Itoa(base + code), nil
+    }
+    if strings.HasPrefix(color, "bright-") {
+        if code, found := colorNames[strings.TrimPrefix(color, "bright-")]; found {
+            return strconv.Itoa(bright + code), nil
+        }
+    }
+    if i, err := strconv.Atoi(color); err == nil && 0 <= i && i <= 255 {
+        return fmt.Sprintf("%d;5;%d", extended, i), nil
+    }
+    if len(color) == 7 && color[0] == '#' {
+        if rgb, err := strconv.ParseUint(color[1:], 16, 32); err == nil {
+            return fmt.Sprintf("%d;2;%d;%d;%d", extended, rgb >> 16, (rgb >> 8) & 0xff, rgb & 0xff), nil
+        }
+    }
+    return "", fmt.Errorf("unknown color %q, expected a color name, bright-<name>, 0-255 or #rrggbb", color)
+}
+
+func ansiStyle(preSufFix PreSufFix) string {
+    var codes []string
+    if preSufFix.Bold {
+        codes = append(codes, "1")
+    }
+    if preSufFix.Italic {
+        codes = append(codes, "3")
+    }
+    if preSufFix.Underline {
+        codes = append(codes, "4")
+    }
+    if code, err := colorCode(preSufFix.Fg, false); err == nil && preSufFix.Fg != "" {
+        codes = append(codes, code)
+    }
+    if code, err := colorCode(preSufFix.Bg, true); err == nil && preSufFix.Bg != "" {
+        codes = append(codes, code)
+    }
+    if len(codes) == 0 {
+        return ""
+    }
+    return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
 func Unquote(str string) string {
     if strUnqouted, err := strconv.Unquote(str); err == nil {
         return strUnqouted
@@ -16,39 +123,33 @@ func Unquote(str string) string {
 }
 
 func preSufFixString(preSufFix PreSufFix, str string) string {
-    return Unquote(preSufFix.Prefix) + str + Unquote(preSufFix.Suffix)
+    prefix := Unquote(preSufFix.Prefix)
+    suffix := Unquote(preSufFix.Suffix)
+    if preSufFix.Icon != "" {
+        prefix += preSufFix.Icon + " "
+    }
+
+    if !colorEnabled {
+        return ansiRe.ReplaceAllString(prefix, "") + str + ansiRe.ReplaceAllString(suffix, "")
+    }
+    if style := ansiStyle(preSufFix); style != "" {
+        return prefix + style + str + "\x1b[0m" + suffix
+    }
+    return prefix + str + suffix
 }
 
 func formatCrumb(crumb Crumb, conf *Config) string {
-    var str string
-    if crumb.marker == "" {
-        str = preSufFixString(conf.UnMarked, crumb.text)
-    } else {
-        str = preSufFixString(conf.Markers[crumb.marker], crumb.text)
+    style := conf.UnMarked
+    if crumb.marker != "" {
+        style = conf.Markers[crumb.marker]
     }
-
-    return str
+    return preSufFixString(style, highlightText(crumb.text, style, conf))
 }
 
-func printCrumbFile(crumbFilePath string, filter func (Crumb) bool, sortFns []func(func (int) Crumb) less, conf *Config) {
+func printCrumbFile(crumbFilePath string, filter func (Crumb) bool, order compareFn, format *compiledFormat, conf *Config) {
     if fileExists(crumbFilePath) {
-        fileContent := readFile(crumbFilePath)
-        crumbs := crumbsFromFileContent(fileContent, conf)
-
-        for _, sortFn := range sortFns {
-            sort.SliceStable(crumbs, sortFn(func (i int) Crumb {
-                return crumbs[i]
-            }))
-        }
-
-        header := preSufFixString(conf.Header, filepath.Join(crumbFilePath, ".."))
-        fmt.Println(header)
-        for _, crumb := range crumbs {
-            if filter(crumb) {
-                crumbString := formatCrumb(crumb, conf)
-                fmt.Printf("%s\n", crumbString)
-            }
-        }
+        file, listed := listCrumbFile(crumbFilePath, filter, order, conf)
+        printListedFile(listedFile{file, listed}, format, conf)
     }
 }
 
@@ -0,0 +1,332 @@
+package crumb
+
+import (
+    "fmt"
+    "io/ioutil"
+    "math"
+    "os"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "sync"
+)
+
+const defaultWalkDepth = 3
+
+func walkDepth(conf *Config) int {
+    if conf.WalkDepth <= 0 {
+        return defaultWalkDepth
+    }
+    return conf.WalkDepth
+}
+
+// A pattern of a .gitignore, only dirs are ever ignored by it so a crumb
+// file listed in .gitignore is still found
+type gitignoreRule struct {
+    dir string
+    pattern string
+    negate bool
+    anchored bool
+}
+
+func readGitignore(dir string) []gitignoreRule {
+    content, err := ioutil.ReadFile(filepath.Join(dir, ".gitignore"))
+    if err != nil {
+        return nil
+    }
+
+    var rules []gitignoreRule
+    for _, line := range strings.Split(string(content), "\n") {
+        line = strings.TrimSpace(line)
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        rule := gitignoreRule{dir: dir}
+        if strings.HasPrefix(line, "!") {
+            rule.negate, line = true, line[1:]
+        }
+        line = strings.TrimPrefix(strings.TrimSuffix(line, "/"), "**/")
+        rule.anchored = strings.Contains(line, "/")
+        rule.pattern = strings.TrimPrefix(line, "/")
+        rules = append(rules, rule)
+    }
+    return rules
+}
+
+func (rule gitignoreRule) matches(path string) bool {
+    if !rule.anchored {
+        matched, _ := filepath.Match(rule.pattern, filepath.Base(path))
+        return matched
+    }
+    rel, err := filepath.Rel(rule.dir, path)
+    if err != nil {
+        return false
+    }
+    matched, _ := filepath.Match(rule.pattern, filepath.ToSlash(rel))
+    return matched
+}
+
+// Rules of the .gitignore files between the repository root and dir, dir's
+// own is read by the walk
+func inheritedGitignore(dir string, conf *Config) []gitignoreRule {
+    repoRoot := findRepoRoot(dir)
+    if conf.Walk.NoGitignore || repoRoot == "" {
+        return nil
+    }
+    var ancestors []string
+    for ancestor := filepath.Dir(dir); strings.HasPrefix(ancestor, repoRoot); ancestor = filepath.Dir(ancestor) {
+        ancestors = append([]string{ancestor}, ancestors...)
+        if ancestor == repoRoot {
+            break
+        }
+    }
+    var rules []gitignoreRule
+    for _, ancestor := range ancestors {
+        rules = append(rules, readGitignore(ancestor)...)
+    }
+    return rules
+}
+
+// The last matching rule decides, as in git
+func gitignored(path string, rules []gitignoreRule) bool {
+    ignored := false
+    for _, rule := range rules {
+        if rule.matches(path) {
+            ignored = !rule.negate
+        }
+    }
+    return ignored
+}
+
+// Globs without a slash match the name of a dir, others its path relative
+// to where the walk started
+func walkIgnored(path string, root string, conf *Config) bool {
+    rel, _ := filepath.Rel(root, path)
+    for _, glob := range conf.Walk.Ignore {
+        target := filepath.Base(path)
+        if strings.Contains(glob, "/") {
+            target = filepath.ToSlash(rel)
+        }
+        if matched, _ := filepath.Match(glob, target); matched {
+            return true
+        }
+    }
+    return false
+}
+
+// One entry of a walked dir, entries keep the order ioutil.ReadDir gives
+type dirEntry struct {
+    Name string
+    Kind string
+}
+
+// What a walk needs of a dir, cached by the dir's modification time
+type dirListing struct {
+    ModTime int64
+    Entries []dirEntry
+    Gitignore bool
+}
+
+func readDirListing(dir string, conf *Config) (dirListing, error) {
+    info, err := os.Stat(dir)
+    if err != nil {
+        return dirListing{}, err
+    }
+    if listing, found := walkCache.get(dir); found && listing.ModTime == info.ModTime().UnixNano() {
+        return listing, nil
+    }
+
+    files, err := ioutil.ReadDir(dir)
+    if err != nil {
+        return dirListing{}, err
+    }
+    listing := dirListing{ModTime: info.ModTime().UnixNano()}
+    for _, file := range files {
+        switch {
+        case file.Name() == conf.CrumbFileName:
+            listing.Entries = append(listing.Entries, dirEntry{file.Name(), "crumb"})
+        case file.Name() == ".gitignore":
+            listing.Gitignore = true
+        case file.Mode() & os.ModeSymlink != 0:
+            listing.Entries = append(listing.Entries, dirEntry{file.Name(), "symlink"})
+        case file.IsDir():
+            listing.Entries = append(listing.Entries, dirEntry{file.Name(), "dir"})
+        }
+    }
+    walkCache.put(dir, listing)
+    return listing, nil
+}
+
+// A found crumb file or a warning, kept in walk order so output does not
+// depend on which worker finished first
+type walkItem struct {
+    path string
+    realPath string
+    warning string
+}
+
+func walkWarning(format string, args ...interface{}) walkItem {
+    return walkItem{warning: fmt.Sprintf("crumb: " + format, args...)}
+}
+
+func walkWorkers(conf *Config) int {
+    if conf.Walk.Workers > 0 {
+        return conf.Walk.Workers
+    }
+    return 4 * runtime.NumCPU()
+}
+
+// Dirs are walked concurrently with at most walkWorkers reading at once.
+// Symlinked dirs are only followed with Walk.Symlinks = "follow", a dir
+// linking back to one of its parents is skipped and a crumb file reached
+// by several paths is listed once.
+func walkCrumbFiles(dir string, maxDepth int, conf *Config) []string {
+    loadWalkCache(conf)
+    workers := make(chan struct{}, walkWorkers(conf))
+    root := filepath.Join(dir)
+
+    var walk func(string, int, []gitignoreRule, []string) []walkItem
+    walk = func (dir string, depth int, rules []gitignoreRule, parents []string) []walkItem {
+        if (depth >= maxDepth) {
+            return nil
+        }
+
+        realDir, err := filepath.EvalSymlinks(dir)
+        if err != nil {
+            return []walkItem{walkWarning("cannot resolve %s: %s", dir, err)}
+        }
+        if containsString(parents, realDir) {
+            return []walkItem{walkWarning("skipping %s, it loops back to %s", dir, realDir)}
+        }
+        parents = append(append([]string{}, parents...), realDir)
+
+        workers <- struct{}{}
+        listing, err := readDirListing(dir, conf)
+        if err == nil && listing.Gitignore && !conf.Walk.NoGitignore {
+            rules = append(append([]gitignoreRule{}, rules...), readGitignore(dir)...)
+        }
+        <-workers
+        if err != nil {
+            return []walkItem{walkWarning("cannot read %s: %s", dir, err)}
+        }
+
+        results := make([][]walkItem, len(listing.Entries))
+        var wg sync.WaitGroup
+        for i, entry := range listing.Entries {
+            path := filepath.Join(dir, entry.Name)
+            switch entry.Kind {
+            case "crumb":
+                results[i] = []walkItem{{path: path, realPath: filepath.Join(realDir, entry.Name)}}
+                continue
+            case "symlink":
+                if conf.Walk.Symlinks != "follow" {
+                    continue
+                }
+                info, err := os.Stat(path)
+                if err != nil {
+                    results[i] = []walkItem{walkWarning("cannot follow %s: %s", path, err)}
+                    continue
+                }
+                if !info.IsDir() {
+                    continue
+                }
+            }
+
+            if walkIgnored(path, root, conf) || gitignored(path, rules) {
+                continue
+            }
+            wg.Add(1)
+            go func (i int, path string) {
+                defer wg.Done()
+                results[i] = walk(path, depth + 1, rules, parents)
+            }(i, path)
+        }
+        wg.Wait()
+
+        var items []walkItem
+        for _, result := range results {
+            items = append(items, result...)
+        }
+        return items
+    }
+    items := walk(root, 0, inheritedGitignore(root, conf), nil)
+    saveWalkCache(root)
+
+    var crumbFilePaths []string
+    found := make(map[string]string)
+    for _, item := range items {
+        if item.warning != "" {
+            fmt.Fprintln(os.Stderr, item.warning)
+        } else if first, seen := found[item.realPath]; seen {
+            fmt.Fprintf(os.Stderr, "crumb: skipping %s, already found as %s\n", item.path, first)
+        } else {
+            found[item.realPath] = item.path
+            crumbFilePaths = append(crumbFilePaths, item.path)
+        }
+    }
+    return crumbFilePaths
+}
+
+// Every crumb file below dir whatever the depth, the configured Walk.Ignore
+// or .gitignore, for changes that must not leave a crumb file behind. Only
+// the dirs ignored by default, such as .git, are still skipped.
+func walkAllCrumbFiles(dir string, conf *Config) []string {
+    all := *conf
+    all.Walk.Ignore = newDefaultConfig().Walk.Ignore
+    all.Walk.NoGitignore = true
+    all.Walk.Cache = false
+    return walkCrumbFiles(dir, math.MaxInt32, &all)
+}
+
+type listedFile struct {
+    file fileData
+    crumbs []listedCrumb
+}
+
+// Reads and filters the crumb files in parallel, the result keeps the order
+// of crumbFilePaths and leaves out missing files
+func listCrumbFiles(crumbFilePaths []string, filter func (Crumb) bool, order compareFn, conf *Config) []listedFile {
+    files := make([]*listedFile, len(crumbFilePaths))
+    forEachParallel(len(crumbFilePaths), walkWorkers(conf), func (i int) {
+        if fileExists(crumbFilePaths[i]) {
+            file, listed := listCrumbFile(crumbFilePaths[i], filter, order, conf)
+            files[i] = &listedFile{file, listed}
+        }
+    })
+
+    var found []listedFile
+    for _, file := range files {
+        if file != nil {
+            found = append(found, *file)
+        }
+    }
+    return found
+}
+
+// Leaves out crumb files without any crumb left after filtering, for
+// Walk.Prune
+func pruneListedFiles(files []listedFile) []listedFile {
+    var pruned []listedFile
+    for _, file := range files {
+        if len(file.crumbs) > 0 {
+            pruned = append(pruned, file)
+        }
+    }
+    return pruned
+}
+
+// Runs fn for 0..n-1 with at most workers running at once
+func forEachParallel(n int, workers int, fn func (int)) {
+    tokens := make(chan struct{}, workers)
+    var wg sync.WaitGroup
+    for i := 0; i < n; i++ {
+        wg.Add(1)
+        tokens <- struct{}{}
+        go func (i int) {
+            defer wg.Done()
+            fn(i)
+            <-tokens
+        }(i)
+    }
+    wg.Wait()
+}
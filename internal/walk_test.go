@@ -0,0 +1,248 @@
+package crumb
+
+import (
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// The walk and read as they were before dirs and files were read in
+// parallel, kept to compare against
+func serialWalkCrumbFiles(dir string, maxDepth int, conf *Config) []string {
+    var crumbFilePaths []string
+    root := filepath.Join(dir)
+
+    var walk func(string, int, []gitignoreRule)
+    walk = func (dir string, depth int, rules []gitignoreRule) {
+        if depth >= maxDepth {
+            return
+        }
+        files, err := ioutil.ReadDir(dir)
+        if err != nil {
+            return
+        }
+        if !conf.Walk.NoGitignore {
+            rules = append(append([]gitignoreRule{}, rules...), readGitignore(dir)...)
+        }
+        for _, file := range files {
+            path := filepath.Join(dir, file.Name())
+            if file.Name() == conf.CrumbFileName {
+                crumbFilePaths = append(crumbFilePaths, path)
+            } else if file.IsDir() && !walkIgnored(path, root, conf) && !gitignored(path, rules) {
+                walk(path, depth + 1, rules)
+            }
+        }
+    }
+    walk(root, 0, inheritedGitignore(root, conf))
+    return crumbFilePaths
+}
+
+func serialReadCrumbFiles(crumbFilePaths []string, filter func(Crumb) bool, order compareFn, conf *Config) []fileCrumbs {
+    var files []fileCrumbs
+    for _, crumbFilePath := range crumbFilePaths {
+        crumbLines := strings.Split(readFile(crumbFilePath), "\n")
+        crumbs, lineNumbers := getCrumbsFromLines(crumbLines, filter, order, conf)
+        idsByLine := crumbIDsByLine(crumbLines, crumbFilePath, conf)
+        var ids []string
+        for _, lineNumber := range lineNumbers {
+            ids = append(ids, idsByLine[lineNumber])
+        }
+        files = append(files, fileCrumbs{path: crumbFilePath, crumbs: crumbs, ids: ids})
+    }
+    return files
+}
+
+const (
+    treeDepth = 4
+    treeCrumbs = 20
+)
+
+// A tree of fanout^treeDepth dirs, every other dir holding a crumb file and
+// files plain files, along with ignored dirs the walk has to skip
+func generateTree(tb testing.TB, fanout int, files int) string {
+    root := tempDir(tb)
+
+    n := 0
+    var generate func(string, int)
+    generate = func (dir string, depth int) {
+        if n % 2 == 0 {
+            var lines []string
+            for i := 0; i < treeCrumbs; i++ {
+                lines = append(lines, fmt.Sprintf("2026-10-%02d 12:00:00 crumb %d of %s", i % 28 + 1, i, dir))
+            }
+            if err := ioutil.WriteFile(filepath.Join(dir, ".crumb"), []byte(strings.Join(lines, "\n") + "\n"), 0644); err != nil {
+                tb.Fatal(err)
+            }
+        }
+        n++
+        for i := 0; i < files; i++ {
+            if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.go", i)), nil, 0644); err != nil {
+                tb.Fatal(err)
+            }
+        }
+        if depth == treeDepth {
+            return
+        }
+        if err := os.MkdirAll(filepath.Join(dir, "node_modules", "dep"), 0755); err != nil {
+            tb.Fatal(err)
+        }
+        for i := 0; i < fanout; i++ {
+            sub := filepath.Join(dir, fmt.Sprintf("dir%d", i))
+            if err := os.Mkdir(sub, 0755); err != nil {
+                tb.Fatal(err)
+            }
+            generate(sub, depth + 1)
+        }
+    }
+    generate(root, 0)
+    return root
+}
+
+func tempDir(tb testing.TB) string {
+    dir, err := ioutil.TempDir("", "crumb-walk")
+    if err != nil {
+        tb.Fatal(err)
+    }
+    tb.Cleanup(func () {
+        os.RemoveAll(dir)
+    })
+    return dir
+}
+
+// A config of its own and a fresh cache home, both gone with the test
+func walkTestConfig(tb testing.TB, workers int, cache bool) *Config {
+    tb.Setenv("XDG_CACHE_HOME", tempDir(tb))
+    conf := newDefaultConfig()
+    conf.WalkDepth = treeDepth + 1
+    conf.Walk.Workers = workers
+    conf.Walk.Cache = cache
+    return conf
+}
+
+func keepAll(_ Crumb) bool {
+    return true
+}
+
+func TestWalkMatchesSerialOrder(t *testing.T) {
+    root := generateTree(t, 3, 0)
+
+    for _, workers := range []int{1, 8} {
+        for _, cache := range []bool{false, true} {
+            conf := walkTestConfig(t, workers, cache)
+            order := buildSorts(nil)
+            want := serialWalkCrumbFiles(root, walkDepth(conf), conf)
+            wantFiles := serialReadCrumbFiles(want, keepAll, order, conf)
+
+            // A second walk with the cache reads the listings back from it
+            for pass := 0; pass < 2; pass++ {
+                got := walkCrumbFiles(root, walkDepth(conf), conf)
+                if !reflect.DeepEqual(got, want) {
+                    t.Fatalf("workers %d, cache %v, pass %d: walked %d files out of order, want %d", workers, cache, pass, len(got), len(want))
+                }
+                if gotFiles := readCrumbFiles(got, keepAll, order, conf); !reflect.DeepEqual(gotFiles, wantFiles) {
+                    t.Fatalf("workers %d, cache %v, pass %d: read crumbs differ from the serial read", workers, cache, pass)
+                }
+            }
+        }
+    }
+}
+
+func TestWalkCacheEvictsRemovedDirs(t *testing.T) {
+    root := generateTree(t, 2, 0)
+    conf := walkTestConfig(t, 0, true)
+    walkCrumbFiles(root, walkDepth(conf), conf)
+
+    removed := filepath.Join(root, "dir0")
+    if err := os.RemoveAll(removed); err != nil {
+        t.Fatal(err)
+    }
+    walkCrumbFiles(root, walkDepth(conf), conf)
+
+    loadWalkCache(conf)
+    for dir, _ := range walkCache.Dirs {
+        if dir == removed || strings.HasPrefix(dir, removed + string(filepath.Separator)) {
+            t.Fatalf("%s is still cached after it was removed", dir)
+        }
+    }
+    if _, found := walkCache.Dirs[filepath.Join(root, "dir1")]; !found {
+        t.Fatalf("%s was evicted while still walked", filepath.Join(root, "dir1"))
+    }
+}
+
+type walkFn func (root string, conf *Config) []fileCrumbs
+
+func serialWalk(root string, conf *Config) []fileCrumbs {
+    return serialReadCrumbFiles(serialWalkCrumbFiles(root, walkDepth(conf), conf), keepAll, buildSorts(nil), conf)
+}
+
+func parallelWalk(root string, conf *Config) []fileCrumbs {
+    return readCrumbFiles(walkCrumbFiles(root, walkDepth(conf), conf), keepAll, buildSorts(nil), conf)
+}
+
+// A cold cache is removed before each walk, a warm one is filled once
+func benchmarkWalk(b *testing.B, walk walkFn, workers int, cache bool, warm bool) {
+    root := generateTree(b, 6, 0)
+    conf := walkTestConfig(b, workers, cache)
+    if warm {
+        walk(root, conf)
+    }
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if cache && !warm {
+            b.StopTimer()
+            os.Remove(walkCachePath())
+            b.StartTimer()
+        }
+        walk(root, conf)
+    }
+}
+
+func BenchmarkWalkSerial(b *testing.B) {
+    benchmarkWalk(b, serialWalk, 1, false, false)
+}
+
+func BenchmarkWalkWorkers1(b *testing.B) {
+    benchmarkWalk(b, parallelWalk, 1, false, false)
+}
+
+func BenchmarkWalkWorkersN(b *testing.B) {
+    benchmarkWalk(b, parallelWalk, 0, false, false)
+}
+
+func BenchmarkWalkCacheCold(b *testing.B) {
+    benchmarkWalk(b, parallelWalk, 0, true, false)
+}
+
+func BenchmarkWalkCacheWarm(b *testing.B) {
+    benchmarkWalk(b, parallelWalk, 0, true, true)
+}
+
+// Only listing dirs, without reading the crumb files, in a tree whose dirs
+// hold as many files as a source tree would. This is where the cache pays.
+func benchmarkListing(b *testing.B, walk func (string, int, *Config) []string, cache bool) {
+    root := generateTree(b, 4, 200)
+    conf := walkTestConfig(b, 0, cache)
+    walk(root, walkDepth(conf), conf)
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        walk(root, walkDepth(conf), conf)
+    }
+}
+
+func BenchmarkListingSerial(b *testing.B) {
+    benchmarkListing(b, serialWalkCrumbFiles, false)
+}
+
+func BenchmarkListingUncached(b *testing.B) {
+    benchmarkListing(b, walkCrumbFiles, false)
+}
+
+func BenchmarkListingCached(b *testing.B) {
+    benchmarkListing(b, walkCrumbFiles, true)
+}
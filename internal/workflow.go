@@ -0,0 +1,108 @@
+package crumb
+
+import (
+    "fmt"
+)
+
+func workflowPosition(marker string, conf *Config) int {
+    for i, state := range conf.Workflow.States {
+        if state == marker {
+            return i
+        }
+    }
+    return -1
+}
+
+func containsString(strs []string, str string) bool {
+    for _, s := range strs {
+        if s == str {
+            return true
+        }
+    }
+    return false
+}
+
+// Without `Transitions` for a state any transition from it is allowed
+func isTransitionAllowed(from string, to string, conf *Config) bool {
+    if from == to {
+        return true
+    }
+    allowed, found := conf.Workflow.Transitions[from]
+    if !found {
+        return true
+    }
+    return containsString(allowed, to)
+}
+
+func nextState(marker string, conf *Config) (string, error) {
+    states := conf.Workflow.States
+    if len(states) == 0 {
+        return "", fmt.Errorf("No workflow configured, add [Workflow] States")
+    }
+    if marker == "" {
+        return states[0], nil
+    }
+    position := workflowPosition(marker, conf)
+    if position == -1 {
+        return "", fmt.Errorf("Marker %s is not a workflow state", marker)
+    }
+    if position == len(states) - 1 {
+        return "", fmt.Errorf("Marker %s is the last workflow state", marker)
+    }
+    return states[position + 1], nil
+}
+
+func prevState(marker string, conf *Config) (string, error) {
+    states := conf.Workflow.States
+    if len(states) == 0 {
+        return "", fmt.Errorf("No workflow configured, add [Workflow] States")
+    }
+    if marker == "" {
+        return "", fmt.Errorf("Unmarked crumbs have no previous workflow state")
+    }
+    position := workflowPosition(marker, conf)
+    if position == -1 {
+        return "", fmt.Errorf("Marker %s is not a workflow state", marker)
+    }
+    if position == 0 {
+        return "", nil
+    }
+    return states[position - 1], nil
+}
+
+func isClosedMarker(marker string, conf *Config) bool {
+    return containsString(conf.Workflow.Closed, marker)
+}
+
+// Crumbs in a Workflow.Closed state are completed, without one configured
+// any marked crumb is. Stats, standup, exports and imports all go by this.
+func isCompletedMarker(marker string, conf *Config) bool {
+    if len(conf.Workflow.Closed) > 0 {
+        return isClosedMarker(marker, conf)
+    }
+    return marker != ""
+}
+
+// Without `Open` every marker not closed counts as open
+func isOpenMarker(marker string, conf *Config) bool {
+    if len(conf.Workflow.Open) > 0 {
+        return containsString(conf.Workflow.Open, marker)
+    }
+    return !isClosedMarker(marker, conf)
+}
+
+func transitionAction(to func(string) (string, error), force bool, conf *Config) func(Crumb) *Crumb {
+    return func (crumb Crumb) *Crumb {
+        marker, err := to(crumb.marker)
+        if err != nil {
+            fmt.Println(err)
+            return &crumb
+        }
+        if !force && !isTransitionAllowed(crumb.marker, marker, conf) {
+            fmt.Printf("Transition from %s to %s is not allowed for \"%s\", use --force\n", crumb.marker, marker, crumb.text)
+            return &crumb
+        }
+        crumb.marker = marker
+        return &crumb
+    }
+}